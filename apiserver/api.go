@@ -19,10 +19,15 @@ import (
 // pass the data to a ConfigurationAPIServicer to perform the required actions, then write the service results to the http response.
 type ConfigurationAPIRouter interface {
 	DeleteConfigurationById(http.ResponseWriter, *http.Request)
+	DiscoverConfigurationById(http.ResponseWriter, *http.Request)
 	GetConfigurationById(http.ResponseWriter, *http.Request)
+	GetConfigurationStatusById(http.ResponseWriter, *http.Request)
 	GetConfigurations(http.ResponseWriter, *http.Request)
+	PollConfigurationById(http.ResponseWriter, *http.Request)
 	PostConfiguration(http.ResponseWriter, *http.Request)
 	PutConfigurationById(http.ResponseWriter, *http.Request)
+	TestConfiguration(http.ResponseWriter, *http.Request)
+	TestConfigurationById(http.ResponseWriter, *http.Request)
 }
 
 // CustomizationAPIRouter defines the required methods for binding the api requests to a responses for the CustomizationAPI
@@ -32,6 +37,34 @@ type CustomizationAPIRouter interface {
 	GetDashboardTemplateByName(http.ResponseWriter, *http.Request)
 }
 
+// HealthAPIRouter defines the required methods for binding the api requests to a responses for the HealthAPI
+// The HealthAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a HealthAPIServicer to perform the required actions, then write the service results to the http response.
+type HealthAPIRouter interface {
+	GetHealth(http.ResponseWriter, *http.Request)
+	GetLiveness(http.ResponseWriter, *http.Request)
+	GetReadiness(http.ResponseWriter, *http.Request)
+}
+
+// DevicesAPIRouter defines the required methods for binding the api requests to a responses for the DevicesAPI
+// The DevicesAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a DevicesAPIServicer to perform the required actions, then write the service results to the http response.
+type DevicesAPIRouter interface {
+	GetDevicesByConfigId(http.ResponseWriter, *http.Request)
+	PreviewAssetFilter(http.ResponseWriter, *http.Request)
+}
+
+// MappingAPIRouter defines the required methods for binding the api requests to a responses for the MappingAPI
+// The MappingAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a MappingAPIServicer to perform the required actions, then write the service results to the http response.
+type MappingAPIRouter interface {
+	DeleteMappingById(http.ResponseWriter, *http.Request)
+	GetMappings(http.ResponseWriter, *http.Request)
+	GetRoomSuggestions(http.ResponseWriter, *http.Request)
+	PostMapping(http.ResponseWriter, *http.Request)
+	PutMappingById(http.ResponseWriter, *http.Request)
+}
+
 // VersionAPIRouter defines the required methods for binding the api requests to a responses for the VersionAPI
 // The VersionAPIRouter implementation should parse necessary information from the http request,
 // pass the data to a VersionAPIServicer to perform the required actions, then write the service results to the http response.
@@ -46,10 +79,15 @@ type VersionAPIRouter interface {
 // and updated with the logic required for the API.
 type ConfigurationAPIServicer interface {
 	DeleteConfigurationById(context.Context, int64) (ImplResponse, error)
+	DiscoverConfigurationById(context.Context, int64) (ImplResponse, error)
 	GetConfigurationById(context.Context, int64) (ImplResponse, error)
+	GetConfigurationStatusById(context.Context, int64) (ImplResponse, error)
 	GetConfigurations(context.Context) (ImplResponse, error)
+	PollConfigurationById(context.Context, int64) (ImplResponse, error)
 	PostConfiguration(context.Context, Configuration) (ImplResponse, error)
 	PutConfigurationById(context.Context, int64, Configuration) (ImplResponse, error)
+	TestConfiguration(context.Context, Configuration) (ImplResponse, error)
+	TestConfigurationById(context.Context, int64) (ImplResponse, error)
 }
 
 // CustomizationAPIServicer defines the api actions for the CustomizationAPI service
@@ -60,6 +98,37 @@ type CustomizationAPIServicer interface {
 	GetDashboardTemplateByName(context.Context, string, string) (ImplResponse, error)
 }
 
+// DevicesAPIServicer defines the api actions for the DevicesAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type DevicesAPIServicer interface {
+	GetDevicesByConfigId(context.Context, int64) (ImplResponse, error)
+	PreviewAssetFilter(context.Context, int64, [][]FilterRule) (ImplResponse, error)
+}
+
+// HealthAPIServicer defines the api actions for the HealthAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type HealthAPIServicer interface {
+	GetHealth(context.Context) (ImplResponse, error)
+	GetLiveness(context.Context) (ImplResponse, error)
+	GetReadiness(context.Context) (ImplResponse, error)
+}
+
+// MappingAPIServicer defines the api actions for the MappingAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type MappingAPIServicer interface {
+	DeleteMappingById(context.Context, int64, int64) (ImplResponse, error)
+	GetMappings(context.Context, int64) (ImplResponse, error)
+	GetRoomSuggestions(context.Context, int64, string) (ImplResponse, error)
+	PostMapping(context.Context, int64, AssetMapping) (ImplResponse, error)
+	PutMappingById(context.Context, int64, int64, AssetMapping) (ImplResponse, error)
+}
+
 // VersionAPIServicer defines the api actions for the VersionAPI service
 // This interface intended to stay up to date with the openapi yaml used to generate it,
 // while the service implementation can be ignored with the .openapi-generator-ignore file
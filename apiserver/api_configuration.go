@@ -57,16 +57,31 @@ func (c *ConfigurationAPIController) Routes() Routes {
 			"/v1/configs/{config-id}",
 			c.DeleteConfigurationById,
 		},
+		"DiscoverConfigurationById": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/{config-id}/discover",
+			c.DiscoverConfigurationById,
+		},
 		"GetConfigurationById": Route{
 			strings.ToUpper("Get"),
 			"/v1/configs/{config-id}",
 			c.GetConfigurationById,
 		},
+		"GetConfigurationStatusById": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/status",
+			c.GetConfigurationStatusById,
+		},
 		"GetConfigurations": Route{
 			strings.ToUpper("Get"),
 			"/v1/configs",
 			c.GetConfigurations,
 		},
+		"PollConfigurationById": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/{config-id}/poll",
+			c.PollConfigurationById,
+		},
 		"PostConfiguration": Route{
 			strings.ToUpper("Post"),
 			"/v1/configs",
@@ -77,6 +92,16 @@ func (c *ConfigurationAPIController) Routes() Routes {
 			"/v1/configs/{config-id}",
 			c.PutConfigurationById,
 		},
+		"TestConfiguration": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/test",
+			c.TestConfiguration,
+		},
+		"TestConfigurationById": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/{config-id}/test",
+			c.TestConfigurationById,
+		},
 	}
 }
 
@@ -101,6 +126,27 @@ func (c *ConfigurationAPIController) DeleteConfigurationById(w http.ResponseWrit
 	EncodeJSONResponse(result.Body, &result.Code, w)
 }
 
+// DiscoverConfigurationById - Runs a discovery
+func (c *ConfigurationAPIController) DiscoverConfigurationById(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.DiscoverConfigurationById(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
 // GetConfigurationById - Get configuration
 func (c *ConfigurationAPIController) GetConfigurationById(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
@@ -122,6 +168,27 @@ func (c *ConfigurationAPIController) GetConfigurationById(w http.ResponseWriter,
 	EncodeJSONResponse(result.Body, &result.Code, w)
 }
 
+// GetConfigurationStatusById - Get runtime status
+func (c *ConfigurationAPIController) GetConfigurationStatusById(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.GetConfigurationStatusById(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
 // GetConfigurations - Get configurations
 func (c *ConfigurationAPIController) GetConfigurations(w http.ResponseWriter, r *http.Request) {
 	result, err := c.service.GetConfigurations(r.Context())
@@ -134,6 +201,27 @@ func (c *ConfigurationAPIController) GetConfigurations(w http.ResponseWriter, r
 	EncodeJSONResponse(result.Body, &result.Code, w)
 }
 
+// PollConfigurationById - Runs a data poll
+func (c *ConfigurationAPIController) PollConfigurationById(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.PollConfigurationById(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
 // PostConfiguration - Creates a configuration
 func (c *ConfigurationAPIController) PostConfiguration(w http.ResponseWriter, r *http.Request) {
 	configurationParam := Configuration{}
@@ -196,3 +284,51 @@ func (c *ConfigurationAPIController) PutConfigurationById(w http.ResponseWriter,
 	// If no error, encode the body and the result code
 	EncodeJSONResponse(result.Body, &result.Code, w)
 }
+
+// TestConfiguration - Tests an unsaved configuration
+func (c *ConfigurationAPIController) TestConfiguration(w http.ResponseWriter, r *http.Request) {
+	configurationParam := Configuration{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&configurationParam); err != nil && !errors.Is(err, io.EOF) {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertConfigurationRequired(configurationParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertConfigurationConstraints(configurationParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.TestConfiguration(r.Context(), configurationParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// TestConfigurationById - Tests a configuration
+func (c *ConfigurationAPIController) TestConfigurationById(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.TestConfigurationById(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
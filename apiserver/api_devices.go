@@ -0,0 +1,123 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// DevicesAPIController binds http requests to an api service and writes the service results to the http response
+type DevicesAPIController struct {
+	service      DevicesAPIServicer
+	errorHandler ErrorHandler
+}
+
+// DevicesAPIOption for how the controller is set up.
+type DevicesAPIOption func(*DevicesAPIController)
+
+// WithDevicesAPIErrorHandler inject ErrorHandler into controller
+func WithDevicesAPIErrorHandler(h ErrorHandler) DevicesAPIOption {
+	return func(c *DevicesAPIController) {
+		c.errorHandler = h
+	}
+}
+
+// NewDevicesAPIController creates a default api controller
+func NewDevicesAPIController(s DevicesAPIServicer, opts ...DevicesAPIOption) Router {
+	controller := &DevicesAPIController{
+		service:      s,
+		errorHandler: DefaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	return controller
+}
+
+// Routes returns all the api routes for the DevicesAPIController
+func (c *DevicesAPIController) Routes() Routes {
+	return Routes{
+		"GetDevicesByConfigId": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/devices",
+			c.GetDevicesByConfigId,
+		},
+		"PreviewAssetFilter": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/{config-id}/filter-preview",
+			c.PreviewAssetFilter,
+		},
+	}
+}
+
+// GetDevicesByConfigId - Get discovered devices
+func (c *DevicesAPIController) GetDevicesByConfigId(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.GetDevicesByConfigId(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PreviewAssetFilter - Previews an asset filter
+func (c *DevicesAPIController) PreviewAssetFilter(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	filterRuleParam := [][]FilterRule{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&filterRuleParam); err != nil && !errors.Is(err, io.EOF) {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	for _, el := range filterRuleParam {
+		for _, rule := range el {
+			if err := AssertFilterRuleRequired(rule); err != nil {
+				c.errorHandler(w, r, err, nil)
+				return
+			}
+		}
+	}
+	result, err := c.service.PreviewAssetFilter(r.Context(), configIdParam, filterRuleParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
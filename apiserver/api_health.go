@@ -0,0 +1,102 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HealthAPIController binds http requests to an api service and writes the service results to the http response
+type HealthAPIController struct {
+	service      HealthAPIServicer
+	errorHandler ErrorHandler
+}
+
+// HealthAPIOption for how the controller is set up.
+type HealthAPIOption func(*HealthAPIController)
+
+// WithHealthAPIErrorHandler inject ErrorHandler into controller
+func WithHealthAPIErrorHandler(h ErrorHandler) HealthAPIOption {
+	return func(c *HealthAPIController) {
+		c.errorHandler = h
+	}
+}
+
+// NewHealthAPIController creates a default api controller
+func NewHealthAPIController(s HealthAPIServicer, opts ...HealthAPIOption) Router {
+	controller := &HealthAPIController{
+		service:      s,
+		errorHandler: DefaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	return controller
+}
+
+// Routes returns all the api routes for the HealthAPIController
+func (c *HealthAPIController) Routes() Routes {
+	return Routes{
+		"GetHealth": Route{
+			strings.ToUpper("Get"),
+			"/v1/health",
+			c.GetHealth,
+		},
+		"GetLiveness": Route{
+			strings.ToUpper("Get"),
+			"/v1/health/live",
+			c.GetLiveness,
+		},
+		"GetReadiness": Route{
+			strings.ToUpper("Get"),
+			"/v1/health/ready",
+			c.GetReadiness,
+		},
+	}
+}
+
+// GetHealth - Health of the app
+func (c *HealthAPIController) GetHealth(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.GetHealth(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetLiveness - Liveness probe
+func (c *HealthAPIController) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.GetLiveness(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetReadiness - Readiness probe
+func (c *HealthAPIController) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.GetReadiness(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
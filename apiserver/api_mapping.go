@@ -0,0 +1,242 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MappingAPIController binds http requests to an api service and writes the service results to the http response
+type MappingAPIController struct {
+	service      MappingAPIServicer
+	errorHandler ErrorHandler
+}
+
+// MappingAPIOption for how the controller is set up.
+type MappingAPIOption func(*MappingAPIController)
+
+// WithMappingAPIErrorHandler inject ErrorHandler into controller
+func WithMappingAPIErrorHandler(h ErrorHandler) MappingAPIOption {
+	return func(c *MappingAPIController) {
+		c.errorHandler = h
+	}
+}
+
+// NewMappingAPIController creates a default api controller
+func NewMappingAPIController(s MappingAPIServicer, opts ...MappingAPIOption) Router {
+	controller := &MappingAPIController{
+		service:      s,
+		errorHandler: DefaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	return controller
+}
+
+// Routes returns all the api routes for the MappingAPIController
+func (c *MappingAPIController) Routes() Routes {
+	return Routes{
+		"DeleteMappingById": Route{
+			strings.ToUpper("Delete"),
+			"/v1/configs/{config-id}/mappings/{mapping-id}",
+			c.DeleteMappingById,
+		},
+		"GetMappings": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/mappings",
+			c.GetMappings,
+		},
+		"GetRoomSuggestions": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/room-suggestions",
+			c.GetRoomSuggestions,
+		},
+		"PostMapping": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/{config-id}/mappings",
+			c.PostMapping,
+		},
+		"PutMappingById": Route{
+			strings.ToUpper("Put"),
+			"/v1/configs/{config-id}/mappings/{mapping-id}",
+			c.PutMappingById,
+		},
+	}
+}
+
+// DeleteMappingById - Deletes an asset mapping
+func (c *MappingAPIController) DeleteMappingById(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	mappingIdParam, err := parseNumericParameter[int64](
+		params["mapping-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.DeleteMappingById(r.Context(), configIdParam, mappingIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetMappings - Get asset mappings
+func (c *MappingAPIController) GetMappings(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.GetMappings(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetRoomSuggestions - Suggest Eliona assets for myStrom rooms
+func (c *MappingAPIController) GetRoomSuggestions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	query := r.URL.Query()
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var projectIdParam string
+	if query.Has("projectId") {
+		param := query.Get("projectId")
+
+		projectIdParam = param
+	} else {
+		c.errorHandler(w, r, &RequiredError{Field: "projectId"}, nil)
+		return
+	}
+	result, err := c.service.GetRoomSuggestions(r.Context(), configIdParam, projectIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PostMapping - Pins a device to an Eliona asset
+func (c *MappingAPIController) PostMapping(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	assetMappingParam := AssetMapping{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&assetMappingParam); err != nil && !errors.Is(err, io.EOF) {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertAssetMappingRequired(assetMappingParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertAssetMappingConstraints(assetMappingParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.PostMapping(r.Context(), configIdParam, assetMappingParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// PutMappingById - Updates an asset mapping
+func (c *MappingAPIController) PutMappingById(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	mappingIdParam, err := parseNumericParameter[int64](
+		params["mapping-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	assetMappingParam := AssetMapping{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&assetMappingParam); err != nil && !errors.Is(err, io.EOF) {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertAssetMappingRequired(assetMappingParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertAssetMappingConstraints(assetMappingParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.PutMappingById(r.Context(), configIdParam, mappingIdParam, assetMappingParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
@@ -0,0 +1,45 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// AssetMapping - Mapping between a myStrom device or room and an Eliona asset in a project.
+type AssetMapping struct {
+
+	// Internal identifier of the mapping (created automatically).
+	Id *int64 `json:"id,omitempty"`
+
+	// myStrom device ID. Either deviceId or roomId is set.
+	DeviceId string `json:"deviceId,omitempty"`
+
+	// myStrom room ID. Either deviceId or roomId is set.
+	RoomId string `json:"roomId,omitempty"`
+
+	// Eliona project ID
+	ProjectId string `json:"projectId,omitempty"`
+
+	// Eliona asset ID
+	AssetId int32 `json:"assetId,omitempty"`
+
+	// Global asset identifier used by the app for the device
+	GlobalAssetId string `json:"globalAssetId,omitempty"`
+
+	// Set if the mapping was created by a user. Pinned mappings are never changed by continuous asset creation.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// AssertAssetMappingRequired checks if the required fields are not zero-ed
+func AssertAssetMappingRequired(obj AssetMapping) error {
+	return nil
+}
+
+// AssertAssetMappingConstraints checks if the values respects the defined constraints
+func AssertAssetMappingConstraints(obj AssetMapping) error {
+	return nil
+}
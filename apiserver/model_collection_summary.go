@@ -0,0 +1,36 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// CollectionSummary - Outcome of a discovery or data poll run.
+type CollectionSummary struct {
+
+	// Number of assets created in Eliona
+	AssetsCreated int32 `json:"assetsCreated,omitempty"`
+
+	// Number of existing assets renamed according to the naming template
+	AssetsRenamed int32 `json:"assetsRenamed,omitempty"`
+
+	// Number of devices whose data was written to Eliona
+	DevicesUpdated int32 `json:"devicesUpdated,omitempty"`
+
+	// Errors that occurred during the run
+	Errors []string `json:"errors,omitempty"`
+}
+
+// AssertCollectionSummaryRequired checks if the required fields are not zero-ed
+func AssertCollectionSummaryRequired(obj CollectionSummary) error {
+	return nil
+}
+
+// AssertCollectionSummaryConstraints checks if the values respects the defined constraints
+func AssertCollectionSummaryConstraints(obj CollectionSummary) error {
+	return nil
+}
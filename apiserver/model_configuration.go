@@ -30,17 +30,59 @@ type Configuration struct {
 	// Timeout in seconds
 	RequestTimeout *int32 `json:"requestTimeout,omitempty"`
 
+	// Maximum number of requests per hour to myStrom API v1, shared by all configurations with the same API key
+	ApiV1RateLimit *int32 `json:"apiV1RateLimit,omitempty"`
+
+	// Maximum number of requests per hour to myStrom API v2, shared by all configurations with the same API key
+	ApiV2RateLimit *int32 `json:"apiV2RateLimit,omitempty"`
+
+	// Minimum change of the power in W that is written to Eliona before the next heartbeat
+	PowerDeadband *float32 `json:"powerDeadband,omitempty"`
+
+	// Minimum change of the temperature in °C that is written to Eliona before the next heartbeat
+	TemperatureDeadband *float32 `json:"temperatureDeadband,omitempty"`
+
+	// Interval in seconds in which all data is written to Eliona, even if unchanged. 0 writes all data at every poll.
+	HeartbeatInterval *int32 `json:"heartbeatInterval,omitempty"`
+
+	// Maximum number of readings kept while Eliona is unreachable. The oldest readings are dropped first. 0 disables buffering.
+	BufferMaxReadings *int32 `json:"bufferMaxReadings,omitempty"`
+
+	// Maximum age in seconds of readings kept while Eliona is unreachable
+	BufferMaxAge *int32 `json:"bufferMaxAge,omitempty"`
+
 	// Array of rules combined by logical OR
 	AssetFilter [][]FilterRule `json:"assetFilter,omitempty"`
 
 	// Set to `true` by the app when running and to `false` when app is stopped
 	Active *bool `json:"active,omitempty"`
 
+	// Set to `true` by the app when myStrom rejected the API key repeatedly. Collecting resumes once the API key is changed.
+	Suspended *bool `json:"suspended,omitempty"`
+
 	// List of Eliona project ids for which this device should collect data. For each project id all smart devices are automatically created as an asset in Eliona. The mapping between Eliona is stored as an asset mapping in the KentixONE app.
 	ProjectIDs *[]string `json:"projectIDs,omitempty"`
 
 	// ID of the last Eliona user who created or updated the configuration
 	UserId *string `json:"userId,omitempty"`
+
+	// myStrom device IDs always created as assets, regardless of the asset filter
+	IncludeDeviceIds *[]string `json:"includeDeviceIds,omitempty"`
+
+	// myStrom device IDs never created as assets, regardless of the asset filter. Takes precedence over includeDeviceIds.
+	ExcludeDeviceIds *[]string `json:"excludeDeviceIds,omitempty"`
+
+	// Additional asset filter per project ID. A device is only created and updated in a project if it also passes the filter of that project. Projects without a filter get all devices.
+	ProjectFilters map[string][][]FilterRule `json:"projectFilters,omitempty"`
+
+	// Existing Eliona assets under which the root asset of the configuration is created, keyed by project ID
+	ParentAssets map[string]ParentAssets `json:"parentAssets,omitempty"`
+
+	// Template for the names of created device assets. Supported placeholders are {name}, {room}, {type} and {id}. If empty, the myStrom device name is used.
+	AssetNameTemplate *string `json:"assetNameTemplate,omitempty"`
+
+	// If set, existing device assets are renamed according to the template at the next discovery. The flag is reset afterwards.
+	RenameExistingAssets *bool `json:"renameExistingAssets,omitempty"`
 }
 
 // AssertConfigurationRequired checks if the required fields are not zero-ed
@@ -48,6 +90,11 @@ func AssertConfigurationRequired(obj Configuration) error {
 	if err := AssertRecurseInterfaceRequired(obj.AssetFilter, AssertFilterRuleRequired); err != nil {
 		return err
 	}
+	for _, filter := range obj.ProjectFilters {
+		if err := AssertRecurseInterfaceRequired(filter, AssertFilterRuleRequired); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
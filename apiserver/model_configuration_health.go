@@ -0,0 +1,53 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// ConfigurationHealth - Collector state of a configuration.
+type ConfigurationHealth struct {
+
+	// ID of the configuration
+	ConfigId int64 `json:"configId"`
+
+	// `collecting` if a collector is running, `starting` if the configuration is enabled but its collector has not started yet, `standby` if another replica collects, `disabled` or `suspended` otherwise
+	State string `json:"state"`
+
+	// Time of the last successful data poll
+	LastPollAt *time.Time `json:"lastPollAt,omitempty"`
+
+	// Error message of the last failed discovery or data poll
+	LastError *string `json:"lastError,omitempty"`
+
+	// Number of failed runs since the last successful discovery or data poll
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+}
+
+// AssertConfigurationHealthRequired checks if the required fields are not zero-ed
+func AssertConfigurationHealthRequired(obj ConfigurationHealth) error {
+	elements := map[string]interface{}{
+		"configId": obj.ConfigId,
+		"state":    obj.State,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertConfigurationHealthConstraints checks if the values respects the defined constraints
+func AssertConfigurationHealthConstraints(obj ConfigurationHealth) error {
+	return nil
+}
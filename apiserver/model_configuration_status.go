@@ -0,0 +1,64 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// ConfigurationStatus - Runtime status of a configuration.
+type ConfigurationStatus struct {
+
+	// Whether the app is currently collecting data for the configuration
+	Active bool `json:"active,omitempty"`
+
+	// Whether collecting was suspended because myStrom rejected the API key repeatedly
+	Suspended bool `json:"suspended,omitempty"`
+
+	// Time of the last successful discovery
+	LastDiscoveryAt *time.Time `json:"lastDiscoveryAt,omitempty"`
+
+	// Time of the last successful data poll
+	LastPollAt *time.Time `json:"lastPollAt,omitempty"`
+
+	// Time the next scheduled discovery is due. Empty if the app is not collecting for the configuration.
+	NextDiscoveryAt *time.Time `json:"nextDiscoveryAt,omitempty"`
+
+	// Time the next scheduled data poll is due. Empty if the app is not collecting for the configuration.
+	NextPollAt *time.Time `json:"nextPollAt,omitempty"`
+
+	// Error message of the last failed discovery or data poll
+	LastError *string `json:"lastError,omitempty"`
+
+	// Time of the last failed discovery or data poll
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+
+	// Number of failed runs since the last successful discovery or data poll
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// Number of supported myStrom devices seen at the last successful discovery, including devices excluded by the asset filter
+	DeviceCount int32 `json:"deviceCount,omitempty"`
+
+	// Number of Eliona assets mapped to the configuration after the last successful discovery
+	AssetCount int32 `json:"assetCount,omitempty"`
+
+	// Number of readings buffered while Eliona was unreachable and not written yet
+	BufferedReadings int64 `json:"bufferedReadings,omitempty"`
+}
+
+// AssertConfigurationStatusRequired checks if the required fields are not zero-ed
+func AssertConfigurationStatusRequired(obj ConfigurationStatus) error {
+	return nil
+}
+
+// AssertConfigurationStatusConstraints checks if the values respects the defined constraints
+func AssertConfigurationStatusConstraints(obj ConfigurationStatus) error {
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// ConnectionTestResult - Result of a connectivity test against the myStrom API.
+type ConnectionTestResult struct {
+
+	// Whether the myStrom API accepted the API key
+	Authenticated bool `json:"authenticated,omitempty"`
+
+	// HTTP status code returned by the myStrom API (0 if no response was received)
+	StatusCode int32 `json:"statusCode,omitempty"`
+
+	// Duration of the request to the myStrom API in milliseconds
+	Latency int64 `json:"latency,omitempty"`
+
+	// Number of devices reported by myStrom, grouped by device type
+	DevicesByType map[string]int32 `json:"devicesByType,omitempty"`
+
+	// Number of supported devices that pass the asset filter and would be created as assets
+	DevicesPassingFilter int32 `json:"devicesPassingFilter,omitempty"`
+
+	// Error that occurred during the test
+	Error *string `json:"error,omitempty"`
+}
+
+// AssertConnectionTestResultRequired checks if the required fields are not zero-ed
+func AssertConnectionTestResultRequired(obj ConnectionTestResult) error {
+	return nil
+}
+
+// AssertConnectionTestResultConstraints checks if the values respects the defined constraints
+func AssertConnectionTestResultConstraints(obj ConnectionTestResult) error {
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// Device - A myStrom device seen at the last discovery.
+type Device struct {
+
+	// myStrom device ID
+	Id string `json:"id,omitempty"`
+
+	// myStrom device type
+	Type string `json:"type,omitempty"`
+
+	// Device name as set in myStrom
+	Name string `json:"name,omitempty"`
+
+	// myStrom room ID
+	RoomId string `json:"roomId,omitempty"`
+
+	// myStrom room name
+	RoomName string `json:"roomName,omitempty"`
+
+	// Last values read from myStrom, keyed by attribute name
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// Time the values were read from myStrom
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+
+	// Whether the asset filter excluded the device. No assets are created for excluded devices.
+	Excluded bool `json:"excluded,omitempty"`
+
+	// Eliona asset ID of the device, keyed by project ID
+	AssetIds map[string]int32 `json:"assetIds,omitempty"`
+}
+
+// AssertDeviceRequired checks if the required fields are not zero-ed
+func AssertDeviceRequired(obj Device) error {
+	return nil
+}
+
+// AssertDeviceConstraints checks if the values respects the defined constraints
+func AssertDeviceConstraints(obj Device) error {
+	return nil
+}
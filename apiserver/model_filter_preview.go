@@ -0,0 +1,53 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// FilterPreview - Outcome of evaluating an asset filter against a discovered device.
+type FilterPreview struct {
+
+	// myStrom device ID
+	Id string `json:"id,omitempty"`
+
+	// myStrom device type
+	Type string `json:"type,omitempty"`
+
+	// Device name as set in myStrom
+	Name string `json:"name,omitempty"`
+
+	// myStrom room name
+	RoomName string `json:"roomName,omitempty"`
+
+	// Whether the filter includes the device
+	Included bool `json:"included,omitempty"`
+
+	// Index of the first rule group of the filter that matched the device. Not set if no group matched or the filter is empty.
+	MatchedRuleGroup *int32 `json:"matchedRuleGroup,omitempty"`
+
+	// Rules of the matched rule group
+	MatchedRules []FilterRule `json:"matchedRules,omitempty"`
+
+	// Set if the device is listed in the include or exclude device list of the configuration, which take precedence over the asset filter
+	Override *string `json:"override,omitempty"`
+}
+
+// AssertFilterPreviewRequired checks if the required fields are not zero-ed
+func AssertFilterPreviewRequired(obj FilterPreview) error {
+	for _, el := range obj.MatchedRules {
+		if err := AssertFilterRuleRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertFilterPreviewConstraints checks if the values respects the defined constraints
+func AssertFilterPreviewConstraints(obj FilterPreview) error {
+	return nil
+}
@@ -11,9 +11,14 @@ package apiserver
 
 // FilterRule - Asset selection rule. Possible parameters are defined in app's README file.
 type FilterRule struct {
+
+	// Device property the rule matches on. One of `id`, `name`, `type`, `room_id` and `room_name`.
 	Parameter string `json:"parameter,omitempty"`
 
 	Regex string `json:"regex,omitempty"`
+
+	// Inverts the rule, so that it matches if the regex does not match
+	Negate bool `json:"negate,omitempty"`
 }
 
 // AssertFilterRuleRequired checks if the required fields are not zero-ed
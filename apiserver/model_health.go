@@ -0,0 +1,65 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// Health - Health of the app.
+type Health struct {
+
+	// `ok` if all checks passed, `unavailable` otherwise
+	Status string `json:"status"`
+
+	// Whether this replica holds the leadership and collects data. Only returned by the aggregated health endpoint.
+	Leader *bool `json:"leader,omitempty"`
+
+	// Results of the readiness checks
+	Checks []HealthCheck `json:"checks,omitempty"`
+
+	// Collector state of each configuration. Only returned by the aggregated health endpoint.
+	Configurations []ConfigurationHealth `json:"configurations,omitempty"`
+}
+
+// AssertHealthRequired checks if the required fields are not zero-ed
+func AssertHealthRequired(obj Health) error {
+	elements := map[string]interface{}{
+		"status": obj.Status,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	for _, el := range obj.Checks {
+		if err := AssertHealthCheckRequired(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Configurations {
+		if err := AssertConfigurationHealthRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertHealthConstraints checks if the values respects the defined constraints
+func AssertHealthConstraints(obj Health) error {
+	for _, el := range obj.Checks {
+		if err := AssertHealthCheckConstraints(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Configurations {
+		if err := AssertConfigurationHealthConstraints(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// HealthCheck - Result of a single readiness check.
+type HealthCheck struct {
+
+	// Name of the check
+	Name string `json:"name"`
+
+	// Whether the check passed
+	Ok bool `json:"ok"`
+
+	// Reason why the check failed
+	Error *string `json:"error,omitempty"`
+}
+
+// AssertHealthCheckRequired checks if the required fields are not zero-ed
+func AssertHealthCheckRequired(obj HealthCheck) error {
+	elements := map[string]interface{}{
+		"name": obj.Name,
+		"ok":   obj.Ok,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	return nil
+}
+
+// AssertHealthCheckConstraints checks if the values respects the defined constraints
+func AssertHealthCheckConstraints(obj HealthCheck) error {
+	return nil
+}
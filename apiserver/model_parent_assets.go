@@ -0,0 +1,30 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// ParentAssets - Existing Eliona assets to place the myStrom assets under.
+type ParentAssets struct {
+
+	// ID of the locational parent asset
+	LocationalAssetId *int32 `json:"locationalAssetId,omitempty"`
+
+	// ID of the functional parent asset
+	FunctionalAssetId *int32 `json:"functionalAssetId,omitempty"`
+}
+
+// AssertParentAssetsRequired checks if the required fields are not zero-ed
+func AssertParentAssetsRequired(obj ParentAssets) error {
+	return nil
+}
+
+// AssertParentAssetsConstraints checks if the values respects the defined constraints
+func AssertParentAssetsConstraints(obj ParentAssets) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// RoomCandidate - An existing Eliona asset that might represent a myStrom room.
+type RoomCandidate struct {
+
+	// Eliona asset ID
+	AssetId int32 `json:"assetId,omitempty"`
+
+	// Name of the asset
+	Name string `json:"name,omitempty"`
+
+	// Asset type of the asset
+	AssetType string `json:"assetType,omitempty"`
+
+	// Similarity of the names between 0 and 1
+	Score float64 `json:"score,omitempty"`
+}
+
+// AssertRoomCandidateRequired checks if the required fields are not zero-ed
+func AssertRoomCandidateRequired(obj RoomCandidate) error {
+	return nil
+}
+
+// AssertRoomCandidateConstraints checks if the values respects the defined constraints
+func AssertRoomCandidateConstraints(obj RoomCandidate) error {
+	return nil
+}
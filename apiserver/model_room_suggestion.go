@@ -0,0 +1,38 @@
+/*
+ * myStrom app API
+ *
+ * API to access and configure the myStrom app.
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// RoomSuggestion - Existing Eliona assets whose names are similar to the name of a myStrom room.
+type RoomSuggestion struct {
+
+	// myStrom room ID
+	RoomId string `json:"roomId,omitempty"`
+
+	// myStrom room name
+	RoomName string `json:"roomName,omitempty"`
+
+	// Candidate assets, best match first
+	Candidates []RoomCandidate `json:"candidates,omitempty"`
+}
+
+// AssertRoomSuggestionRequired checks if the required fields are not zero-ed
+func AssertRoomSuggestionRequired(obj RoomSuggestion) error {
+	for _, el := range obj.Candidates {
+		if err := AssertRoomCandidateRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertRoomSuggestionConstraints checks if the values respects the defined constraints
+func AssertRoomSuggestionConstraints(obj RoomSuggestion) error {
+	return nil
+}
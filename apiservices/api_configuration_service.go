@@ -19,8 +19,15 @@ import (
 	"context"
 	"errors"
 	"mystrom/apiserver"
+	"mystrom/broker"
+	"mystrom/collector"
 	"mystrom/conf"
+	"mystrom/leader"
+	"mystrom/metrics"
 	"net/http"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
 // ConfigurationApiService is a service that implements the logic for the ConfigurationApiServicer
@@ -47,6 +54,7 @@ func (s *ConfigurationApiService) PostConfiguration(ctx context.Context, config
 	if err != nil {
 		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 	}
+	collector.Apply(insertedConfig)
 	return apiserver.Response(http.StatusCreated, insertedConfig), nil
 }
 
@@ -61,12 +69,25 @@ func (s *ConfigurationApiService) GetConfigurationById(ctx context.Context, conf
 	return apiserver.Response(http.StatusOK, config), nil
 }
 
+func (s *ConfigurationApiService) GetConfigurationStatusById(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	status, err := conf.GetConfigStatus(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	status.NextDiscoveryAt, status.NextPollAt = collector.NextRuns(configId)
+	return apiserver.Response(http.StatusOK, status), nil
+}
+
 func (s *ConfigurationApiService) PutConfigurationById(ctx context.Context, configId int64, config apiserver.Configuration) (apiserver.ImplResponse, error) {
 	config.Id = &configId
 	upsertedConfig, err := conf.UpsertConfig(ctx, config)
 	if err != nil {
 		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 	}
+	collector.Apply(upsertedConfig)
 	return apiserver.Response(http.StatusCreated, upsertedConfig), nil
 }
 
@@ -78,5 +99,101 @@ func (s *ConfigurationApiService) DeleteConfigurationById(ctx context.Context, c
 	if err != nil {
 		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 	}
+	collector.Stop(configId)
+	collector.Forget(configId)
+	metrics.Forget(configId)
 	return apiserver.ImplResponse{Code: http.StatusNoContent}, nil
 }
+
+func (s *ConfigurationApiService) TestConfigurationById(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	return apiserver.Response(http.StatusOK, testConnection(ctx, *config)), nil
+}
+
+func (s *ConfigurationApiService) TestConfiguration(ctx context.Context, config apiserver.Configuration) (apiserver.ImplResponse, error) {
+	if config.RequestTimeout == nil {
+		config.RequestTimeout = common.Ptr(conf.DefaultRequestTimeout)
+	}
+	if config.ApiV1RateLimit == nil {
+		config.ApiV1RateLimit = common.Ptr(conf.DefaultApiV1RateLimit)
+	}
+	if config.ApiV2RateLimit == nil {
+		config.ApiV2RateLimit = common.Ptr(conf.DefaultApiV2RateLimit)
+	}
+	return apiserver.Response(http.StatusOK, testConnection(ctx, config)), nil
+}
+
+func testConnection(ctx context.Context, config apiserver.Configuration) apiserver.ConnectionTestResult {
+	test, err := broker.TestConnection(broker.Interactive(ctx), config)
+	result := apiserver.ConnectionTestResult{
+		Authenticated:        test.Authenticated,
+		StatusCode:           int32(test.StatusCode),
+		Latency:              test.Latency.Milliseconds(),
+		DevicesByType:        make(map[string]int32),
+		DevicesPassingFilter: int32(test.DevicesPassingFilter),
+	}
+	for deviceType, count := range test.DevicesByType {
+		result.DevicesByType[deviceType] = int32(count)
+	}
+	if err != nil {
+		log.Warn("services", "testing configuration %v: %v", common.Val(config.Id), err)
+		result.Error = common.Ptr(err.Error())
+	}
+	return result
+}
+
+func (s *ConfigurationApiService) DiscoverConfigurationById(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	if !leader.IsLeader() {
+		// Only the leader's runs are serialized with its collecting.
+		return apiserver.ImplResponse{Code: http.StatusServiceUnavailable}, nil
+	}
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	summary, err := collector.Discover(broker.Interactive(ctx), *config)
+	if errors.Is(err, collector.ErrRunInProgress) {
+		return apiserver.ImplResponse{Code: http.StatusConflict}, nil
+	}
+	return apiserver.Response(http.StatusOK, collectionSummary(summary)), nil
+}
+
+func (s *ConfigurationApiService) PollConfigurationById(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	if !leader.IsLeader() {
+		// Only the leader's runs are serialized with its collecting.
+		return apiserver.ImplResponse{Code: http.StatusServiceUnavailable}, nil
+	}
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	summary, err := collector.Poll(broker.Interactive(ctx), *config)
+	if errors.Is(err, collector.ErrRunInProgress) {
+		return apiserver.ImplResponse{Code: http.StatusConflict}, nil
+	}
+	return apiserver.Response(http.StatusOK, collectionSummary(summary)), nil
+}
+
+func collectionSummary(summary collector.Summary) apiserver.CollectionSummary {
+	result := apiserver.CollectionSummary{
+		AssetsCreated:  int32(summary.AssetsCreated),
+		AssetsRenamed:  int32(summary.AssetsRenamed),
+		DevicesUpdated: int32(summary.DevicesUpdated),
+	}
+	for _, err := range summary.Errors {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	return result
+}
@@ -36,7 +36,7 @@ func NewCustomizationApiService() apiserver.CustomizationAPIServicer {
 // GetDashboardTemplateByName - Get a full dashboard template
 func (s *CustomizationApiService) GetDashboardTemplateByName(ctx context.Context, dashboardTemplateName string, projectId string) (apiserver.ImplResponse, error) {
 	if dashboardTemplateName == "myStrom" {
-		dashboard, err := eliona.GetDashboard(projectId)
+		dashboard, err := eliona.GetDashboard(ctx, projectId)
 		if err != nil {
 			return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 		}
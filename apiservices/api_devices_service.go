@@ -0,0 +1,125 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package apiservices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mystrom/apiserver"
+	"mystrom/collector"
+	"mystrom/conf"
+	"mystrom/model"
+	"net/http"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+)
+
+// DevicesApiService is a service that implements the logic for the DevicesApiServicer
+// This service should implement the business logic for every endpoint for the DevicesApi API.
+// Include any external packages or services that will be required by this service.
+type DevicesApiService struct {
+}
+
+// NewDevicesApiService creates a default api service
+func NewDevicesApiService() apiserver.DevicesAPIServicer {
+	return &DevicesApiService{}
+}
+
+func (s *DevicesApiService) GetDevicesByConfigId(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	if _, err := conf.GetConfig(ctx, configId); errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	} else if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	states, err := collector.Devices(ctx, configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	assets, err := conf.GetAssets(ctx, configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	assetIds := make(map[string]map[string]int32) // GAI -> project ID -> asset ID
+	for _, a := range assets {
+		if !a.AssetID.Valid {
+			continue
+		}
+		if assetIds[a.GlobalAssetID] == nil {
+			assetIds[a.GlobalAssetID] = make(map[string]int32)
+		}
+		assetIds[a.GlobalAssetID][a.ProjectID] = a.AssetID.Int32
+	}
+
+	devices := make([]apiserver.Device, 0, len(states))
+	for _, state := range states {
+		devices = append(devices, apiserver.Device{
+			Id:        state.ID,
+			Type:      state.Type,
+			Name:      state.Name,
+			RoomId:    state.RoomID,
+			RoomName:  state.RoomName,
+			Values:    state.Values,
+			UpdatedAt: state.UpdatedAt,
+			Excluded:  state.Excluded,
+			AssetIds:  assetIds[state.GAI],
+		})
+	}
+	return apiserver.Response(http.StatusOK, devices), nil
+}
+
+func (s *DevicesApiService) PreviewAssetFilter(ctx context.Context, configId int64, filter [][]apiserver.FilterRule) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	states, err := collector.Devices(ctx, configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	previews := make([]apiserver.FilterPreview, 0, len(states))
+	for _, state := range states {
+		preview := apiserver.FilterPreview{
+			Id:       state.ID,
+			Type:     state.Type,
+			Name:     state.Name,
+			RoomName: state.RoomName,
+		}
+		if included, ok := model.DeviceListOverride(*config, state.ID); ok {
+			preview.Included = included
+			preview.Override = common.Ptr("exclude")
+			if included {
+				preview.Override = common.Ptr("include")
+			}
+			previews = append(previews, preview)
+			continue
+		}
+		included, group, err := model.MatchFilter(state.Properties, filter)
+		if err != nil {
+			return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("evaluating filter: %v", err)
+		}
+		preview.Included = included
+		if group >= 0 {
+			preview.MatchedRuleGroup = common.Ptr(int32(group))
+			preview.MatchedRules = filter[group]
+		}
+		previews = append(previews, preview)
+	}
+	return apiserver.Response(http.StatusOK, previews), nil
+}
@@ -0,0 +1,116 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package apiservices
+
+import (
+	"context"
+	"mystrom/apiserver"
+	"mystrom/collector"
+	"mystrom/conf"
+	"mystrom/eliona"
+	"mystrom/leader"
+	"mystrom/output"
+	"net/http"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// checkTimeout bounds each readiness check, so that probes answer before they time out.
+const checkTimeout = 3 * time.Second
+
+type HealthApiService struct {
+}
+
+func NewHealthApiService() apiserver.HealthAPIServicer {
+	return &HealthApiService{}
+}
+
+func (s *HealthApiService) GetLiveness(ctx context.Context) (apiserver.ImplResponse, error) {
+	return apiserver.Response(http.StatusOK, apiserver.Health{Status: "ok"}), nil
+}
+
+func (s *HealthApiService) GetReadiness(ctx context.Context) (apiserver.ImplResponse, error) {
+	health := readiness(ctx)
+	if health.Status != "ok" {
+		return apiserver.Response(http.StatusServiceUnavailable, health), nil
+	}
+	return apiserver.Response(http.StatusOK, health), nil
+}
+
+func (s *HealthApiService) GetHealth(ctx context.Context) (apiserver.ImplResponse, error) {
+	health := readiness(ctx)
+	health.Leader = common.Ptr(leader.IsLeader())
+	configs, err := conf.GetConfigs(ctx)
+	if err != nil {
+		// Already reported by the database check.
+		log.Debug("services", "getting configs for health: %v", err)
+	}
+	for _, config := range configs {
+		status, err := conf.GetConfigStatus(ctx, *config.Id)
+		if err != nil {
+			log.Debug("services", "getting status of config %v for health: %v", *config.Id, err)
+			status = &apiserver.ConfigurationStatus{}
+		}
+		health.Configurations = append(health.Configurations, apiserver.ConfigurationHealth{
+			ConfigId:            *config.Id,
+			State:               collectorState(config),
+			LastPollAt:          status.LastPollAt,
+			LastError:           status.LastError,
+			ConsecutiveFailures: status.ConsecutiveFailures,
+		})
+	}
+	return apiserver.Response(http.StatusOK, health), nil
+}
+
+func readiness(ctx context.Context) apiserver.Health {
+	health := apiserver.Health{Status: "ok"}
+	for _, check := range []struct {
+		name  string
+		check func(context.Context) error
+	}{
+		{"database", conf.Ping},
+		{"eliona-api", eliona.Ping},
+		{"output-listener", func(context.Context) error { return output.Status() }},
+	} {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := check.check(checkCtx)
+		cancel()
+		result := apiserver.HealthCheck{Name: check.name, Ok: err == nil}
+		if err != nil {
+			result.Error = common.Ptr(err.Error())
+			health.Status = "unavailable"
+		}
+		health.Checks = append(health.Checks, result)
+	}
+	return health
+}
+
+func collectorState(config apiserver.Configuration) string {
+	switch {
+	case conf.IsConfigSuspended(config):
+		return "suspended"
+	case !conf.IsConfigEnabled(config):
+		return "disabled"
+	case !leader.IsLeader():
+		return "standby"
+	case collector.Running(*config.Id):
+		return "collecting"
+	default:
+		return "starting"
+	}
+}
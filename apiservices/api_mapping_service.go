@@ -0,0 +1,207 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package apiservices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mystrom/apiserver"
+	"mystrom/appdb"
+	"mystrom/collector"
+	"mystrom/conf"
+	"mystrom/eliona"
+	"mystrom/model"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/eliona-smart-building-assistant/go-eliona/asset"
+	"github.com/volatiletech/null/v8"
+)
+
+// MappingApiService is a service that implements the logic for the MappingApiServicer
+// This service should implement the business logic for every endpoint for the MappingApi API.
+// Include any external packages or services that will be required by this service.
+type MappingApiService struct {
+}
+
+// NewMappingApiService creates a default api service
+func NewMappingApiService() apiserver.MappingAPIServicer {
+	return &MappingApiService{}
+}
+
+func (s *MappingApiService) GetMappings(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	if _, err := conf.GetConfig(ctx, configId); errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	} else if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	assets, err := conf.GetAssets(ctx, configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	mappings := make([]apiserver.AssetMapping, 0, len(assets))
+	for _, a := range assets {
+		mappings = append(mappings, apiMappingFromDbAsset(a))
+	}
+	return apiserver.Response(http.StatusOK, mappings), nil
+}
+
+func (s *MappingApiService) PostMapping(ctx context.Context, configId int64, mapping apiserver.AssetMapping) (apiserver.ImplResponse, error) {
+	dbAsset := appdb.Asset{ConfigurationID: configId}
+	return pinMapping(ctx, dbAsset, mapping, http.StatusCreated)
+}
+
+func (s *MappingApiService) PutMappingById(ctx context.Context, configId int64, mappingId int64, mapping apiserver.AssetMapping) (apiserver.ImplResponse, error) {
+	dbAsset, err := conf.GetAsset(ctx, configId, mappingId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	return pinMapping(ctx, *dbAsset, mapping, http.StatusOK)
+}
+
+func (s *MappingApiService) GetRoomSuggestions(ctx context.Context, configId int64, projectId string) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	if !slices.Contains(conf.ProjIds(*config), projectId) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("project %v is not configured", projectId)
+	}
+	rooms, err := collector.Rooms(ctx, configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	candidates, err := eliona.SuggestRooms(ctx, projectId, rooms)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	suggestions := make([]apiserver.RoomSuggestion, 0, len(rooms))
+	for roomID, roomName := range rooms {
+		suggestion := apiserver.RoomSuggestion{
+			RoomId:     roomID,
+			RoomName:   roomName,
+			Candidates: []apiserver.RoomCandidate{},
+		}
+		for _, c := range candidates[roomID] {
+			suggestion.Candidates = append(suggestion.Candidates, apiserver.RoomCandidate{
+				AssetId:   c.AssetID,
+				Name:      c.Name,
+				AssetType: c.AssetType,
+				Score:     c.Score,
+			})
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].RoomName < suggestions[j].RoomName
+	})
+	return apiserver.Response(http.StatusOK, suggestions), nil
+}
+
+func (s *MappingApiService) DeleteMappingById(ctx context.Context, configId int64, mappingId int64) (apiserver.ImplResponse, error) {
+	err := conf.DeleteAsset(ctx, configId, mappingId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	return apiserver.ImplResponse{Code: http.StatusNoContent}, nil
+}
+
+// pinMapping validates the mapping requested by the user and stores it as pinned.
+func pinMapping(ctx context.Context, dbAsset appdb.Asset, mapping apiserver.AssetMapping, code int) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, dbAsset.ConfigurationID)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	if !slices.Contains(conf.ProjIds(*config), mapping.ProjectId) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("project %v is not configured", mapping.ProjectId)
+	}
+	var gai, providerID string
+	switch {
+	case mapping.DeviceId != "" && mapping.RoomId != "":
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("either deviceId or roomId must be set, not both")
+	case mapping.DeviceId != "":
+		device, ok, err := collector.Device(ctx, dbAsset.ConfigurationID, mapping.DeviceId)
+		if err != nil {
+			return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+		}
+		if !ok {
+			return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("device %v not seen at the last discovery", mapping.DeviceId)
+		}
+		gai, providerID = device.GAI, device.ID
+	case mapping.RoomId != "":
+		rooms, err := collector.Rooms(ctx, dbAsset.ConfigurationID)
+		if err != nil {
+			return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+		}
+		if _, ok := rooms[mapping.RoomId]; !ok {
+			return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("room %v not seen at the last discovery", mapping.RoomId)
+		}
+		room := model.Room{ID: mapping.RoomId}
+		gai, providerID = room.GetGAI(), room.ID
+	default:
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("either deviceId or roomId must be set")
+	}
+	exists, err := asset.ExistAsset(mapping.AssetId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, fmt.Errorf("checking asset %v: %v", mapping.AssetId, err)
+	}
+	if !exists {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, fmt.Errorf("asset %v does not exist in Eliona", mapping.AssetId)
+	}
+
+	dbAsset.ProjectID = mapping.ProjectId
+	dbAsset.GlobalAssetID = gai
+	dbAsset.ProviderID = providerID
+	dbAsset.AssetID = null.Int32From(mapping.AssetId)
+	pinned, err := conf.PinAsset(ctx, dbAsset)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	return apiserver.Response(code, apiMappingFromDbAsset(pinned)), nil
+}
+
+func apiMappingFromDbAsset(dbAsset *appdb.Asset) apiserver.AssetMapping {
+	mapping := apiserver.AssetMapping{
+		Id:            &dbAsset.ID,
+		ProjectId:     dbAsset.ProjectID,
+		AssetId:       dbAsset.AssetID.Int32,
+		GlobalAssetId: dbAsset.GlobalAssetID,
+		Pinned:        dbAsset.Pinned,
+	}
+	if room := (model.Room{ID: dbAsset.ProviderID}); dbAsset.GlobalAssetID == room.GetGAI() {
+		mapping.RoomId = dbAsset.ProviderID
+	} else {
+		mapping.DeviceId = dbAsset.ProviderID
+	}
+	return mapping
+}
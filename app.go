@@ -17,19 +17,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mystrom/apiserver"
 	"mystrom/apiservices"
 	"mystrom/appdb"
 	"mystrom/broker"
+	"mystrom/collector"
 	"mystrom/conf"
 	"mystrom/eliona"
-	"mystrom/model"
+	"mystrom/leader"
+	"mystrom/metrics"
+	"net"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
 	"github.com/eliona-smart-building-assistant/go-eliona/app"
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
 	"github.com/eliona-smart-building-assistant/go-eliona/dashboard"
@@ -40,9 +45,7 @@ import (
 	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
-func initialize() {
-	ctx := context.Background()
-
+func initialize(ctx context.Context) {
 	conn := db.NewInitConnectionWithContextAndApplicationName(ctx, app.AppName())
 	defer conn.Close(ctx)
 
@@ -55,12 +58,76 @@ func initialize() {
 	app.Patch(conn, app.AppName(), "010100",
 		asset.InitAssetTypeFiles("resources/asset-types/*.json"),
 	)
+
+	app.Patch(conn, app.AppName(), "010200",
+		app.ExecSqlFile("conf/patch_010200.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010300",
+		app.ExecSqlFile("conf/patch_010300.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010400",
+		app.ExecSqlFile("conf/patch_010400.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010500",
+		app.ExecSqlFile("conf/patch_010500.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010600",
+		app.ExecSqlFile("conf/patch_010600.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010700",
+		app.ExecSqlFile("conf/patch_010700.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010800",
+		app.ExecSqlFile("conf/patch_010800.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "010900",
+		app.ExecSqlFile("conf/patch_010900.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "011000",
+		app.ExecSqlFile("conf/patch_011000.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "011100",
+		app.ExecSqlFile("conf/patch_011100.sql"),
+	)
+
+	app.Patch(conn, app.AppName(), "011200",
+		app.ExecSqlFile("conf/patch_011200.sql"),
+	)
 }
 
 var once sync.Once
 
-func collectData() {
-	configs, err := conf.GetConfigs(context.Background())
+// loop calls the function in the interval until the context is cancelled.
+func loop(ctx context.Context, function func(context.Context), interval time.Duration) {
+	for ctx.Err() == nil {
+		function(ctx)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func collectData(ctx context.Context) {
+	metrics.SetLeader(leader.IsLeader())
+	if !leader.IsLeader() {
+		// Standby: stop collecting in case the leadership was lost.
+		collector.Sync(nil)
+		return
+	}
+	configs, err := conf.GetConfigs(ctx)
+	if ctx.Err() != nil {
+		return
+	}
 	if err != nil {
 		log.Fatal("conf", "Couldn't read configs from DB: %v", err)
 		return
@@ -73,15 +140,15 @@ func collectData() {
 	}
 
 	for _, config := range configs {
-		if !conf.IsConfigEnabled(config) {
+		if !conf.IsConfigEnabled(config) || conf.IsConfigSuspended(config) {
 			if conf.IsConfigActive(config) {
-				conf.SetConfigActiveState(context.Background(), config, false)
+				conf.SetConfigActiveState(ctx, config, false)
 			}
 			continue
 		}
 
 		if !conf.IsConfigActive(config) {
-			conf.SetConfigActiveState(context.Background(), config, true)
+			conf.SetConfigActiveState(ctx, config, true)
 			log.Info("conf", "Collecting initialized with Configuration %d:\n"+
 				"Enable: %t\n"+
 				"Refresh Interval: %d\n"+
@@ -93,94 +160,46 @@ func collectData() {
 				*config.RequestTimeout,
 				*config.ProjectIDs)
 		}
-
-		common.RunOnceWithParam(func(config apiserver.Configuration) {
-			log.Info("main", "Collecting %d started.", *config.Id)
-			if err := collectResources(config); err != nil {
-				return // Error is handled in the method itself.
-			}
-			pollTicker := time.NewTicker(time.Second * time.Duration(config.DataPollInterval))
-			defer pollTicker.Stop()
-
-			done := time.After(time.Second * time.Duration(config.RefreshInterval))
-			for {
-				select {
-				case <-pollTicker.C:
-					pollData(config)
-				case <-done:
-					log.Info("main", "Collecting %d finished.", *config.Id)
-					return
-				}
-			}
-		}, config, *config.Id)
 	}
+	collector.Sync(configs)
 }
 
-func collectResources(config apiserver.Configuration) error {
-	var _ asset.FunctionalNode = (*model.Switch)(nil)
-	root, err := broker.GetDevices(config)
-	if err != nil {
-		log.Error("broker", "getting root: %v", err)
-		return err
-	}
-	if err := eliona.CreateAssets(config, &root); err != nil {
-		log.Error("eliona", "creating assets: %v", err)
-		return err
+// handleOutput passes an output change from Eliona to the device.
+func handleOutput(ctx context.Context, output api.Data) {
+	if !leader.IsLeader() {
+		// Every replica receives the outputs, but only the leader acts on them.
+		return
 	}
-	if err := eliona.UpsertSwitchData(config, root.GetDevices()); err != nil {
-		log.Error("eliona", "inserting data into Eliona: %v", err)
-		return err
+	metrics.OutputCommand()
+	// Whether the device takes over the value or not, the next poll writes its state.
+	eliona.ForgetWritten(output.AssetId)
+	asset, err := conf.GetAssetById(ctx, output.AssetId)
+	if err != nil {
+		log.Error("conf", "getting asset by assetID %v: %v", output.AssetId, err)
+		return
 	}
-	return nil
-}
-
-func pollData(config apiserver.Configuration) {
-	devices, err := broker.GetData(config)
+	config, err := conf.GetConfigForAsset(ctx, asset)
 	if err != nil {
-		log.Error("broker", "getting data: %v", err)
+		log.Error("conf", "getting configuration for asset id %v: %v", asset.AssetID.Int32, err)
 		return
 	}
-	if err := eliona.UpsertSwitchData(config, devices); err != nil {
-		log.Error("eliona", "inserting data into Eliona: %v", err)
+	if in, err := collector.InProject(ctx, config, asset.ProjectID, asset.GlobalAssetID); err != nil {
+		log.Error("collector", "checking project of asset id %v: %v", asset.AssetID.Int32, err)
+		return
+	} else if !in {
+		log.Warn("main", "ignoring output for asset id %v: device %v does not belong to project %v", asset.AssetID.Int32, asset.ProviderID, asset.ProjectID)
 		return
 	}
-}
-
-// listenForOutputChanges listens to output attribute changes from Eliona.
-func listenForOutputChanges() {
-	for { // We want to restart listening in case something breaks.
-		outputs, err := eliona.ListenForOutputChanges()
-		if err != nil {
-			log.Error("eliona", "listening for output changes: %v", err)
-			return
-		}
-		for output := range outputs {
-			if cr := output.ClientReference.Get(); cr != nil && *cr == eliona.ClientReference {
-				// Just an echoed value this app sent.
-				continue
-			}
-			asset, err := conf.GetAssetById(output.AssetId)
-			if err != nil {
-				log.Error("conf", "getting asset by assetID %v: %v", output.AssetId, err)
-				continue
-			}
-			config, err := conf.GetConfigForAsset(asset)
-			if err != nil {
-				log.Error("conf", "getting configuration for asset id %v: %v", asset.AssetID.Int32, err)
-				continue
-			}
-			if err := outputData(asset, config, output.Data); err != nil {
-				log.Error("conf", "outputting data (%v) for config %v, assetId %v and device id %v: %v", output.Data, config.Id, asset.AssetID.Int32, asset.ProviderID, err)
-				continue
-			}
-			pollData(config)
-		}
-		time.Sleep(time.Second * 5) // Give the server a little break.
+	if err := outputData(ctx, asset, config, output.Data); err != nil {
+		log.Error("conf", "outputting data (%v) for config %v, assetId %v and device id %v: %v", output.Data, config.Id, asset.AssetID.Int32, asset.ProviderID, err)
+		return
 	}
+	// The device's new state is read by a poll shared with other outputs of the configuration.
+	collector.RequestPoll(*config.Id)
 }
 
 // outputData implements passing output data to broker.
-func outputData(asset appdb.Asset, config apiserver.Configuration, data map[string]interface{}) error {
+func outputData(ctx context.Context, asset appdb.Asset, config apiserver.Configuration, data map[string]interface{}) error {
 	val, ok := data["relay"]
 	if !ok {
 		return fmt.Errorf("data does not contain \"relay\": %v", data)
@@ -198,18 +217,38 @@ func outputData(asset appdb.Asset, config apiserver.Configuration, data map[stri
 		return fmt.Errorf("output: got value of unknown type: (%T) %v", val, val)
 	}
 
-	return broker.PostData(config, asset.ProviderID, value)
+	return broker.PostData(ctx, config, asset.ProviderID, value)
 }
 
-// listenApi starts the API server and listen for requests
-func listenApi() {
-	err := http.ListenAndServe(":"+common.Getenv("API_SERVER_PORT", "3000"),
-		frontend.NewEnvironmentHandler(
-			utilshttp.NewCORSEnabledHandler(
-				apiserver.NewRouter(
-					apiserver.NewConfigurationAPIController(apiservices.NewConfigurationApiService()),
-					apiserver.NewVersionAPIController(apiservices.NewVersionApiService()),
-					apiserver.NewCustomizationAPIController(apiservices.NewCustomizationApiService()),
-				))))
-	log.Fatal("main", "API server: %v", err)
+// listenApi starts the API server and listen for requests until the context is cancelled
+func listenApi(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/", frontend.NewEnvironmentHandler(
+		utilshttp.NewCORSEnabledHandler(
+			apiserver.NewRouter(
+				apiserver.NewConfigurationAPIController(apiservices.NewConfigurationApiService()),
+				apiserver.NewDevicesAPIController(apiservices.NewDevicesApiService()),
+				apiserver.NewMappingAPIController(apiservices.NewMappingApiService()),
+				apiserver.NewVersionAPIController(apiservices.NewVersionApiService()),
+				apiserver.NewCustomizationAPIController(apiservices.NewCustomizationApiService()),
+				apiserver.NewHealthAPIController(apiservices.NewHealthApiService()),
+			))))
+	server := &http.Server{
+		Addr:    ":" + common.Getenv("API_SERVER_PORT", "3000"),
+		Handler: mux,
+		// Requests in progress are cancelled together with the app.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error("main", "shutting down API server: %v", err)
+		}
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal("main", "API server: %v", err)
+	}
 }
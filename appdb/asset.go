@@ -30,6 +30,7 @@ type Asset struct {
 	GlobalAssetID   string     `boil:"global_asset_id" json:"global_asset_id" toml:"global_asset_id" yaml:"global_asset_id"`
 	ProviderID      string     `boil:"provider_id" json:"provider_id" toml:"provider_id" yaml:"provider_id"`
 	AssetID         null.Int32 `boil:"asset_id" json:"asset_id,omitempty" toml:"asset_id" yaml:"asset_id,omitempty"`
+	Pinned          bool       `boil:"pinned" json:"pinned" toml:"pinned" yaml:"pinned"`
 
 	R *assetR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L assetL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -42,6 +43,7 @@ var AssetColumns = struct {
 	GlobalAssetID   string
 	ProviderID      string
 	AssetID         string
+	Pinned          string
 }{
 	ID:              "id",
 	ConfigurationID: "configuration_id",
@@ -49,6 +51,7 @@ var AssetColumns = struct {
 	GlobalAssetID:   "global_asset_id",
 	ProviderID:      "provider_id",
 	AssetID:         "asset_id",
+	Pinned:          "pinned",
 }
 
 var AssetTableColumns = struct {
@@ -58,6 +61,7 @@ var AssetTableColumns = struct {
 	GlobalAssetID   string
 	ProviderID      string
 	AssetID         string
+	Pinned          string
 }{
 	ID:              "asset.id",
 	ConfigurationID: "asset.configuration_id",
@@ -65,6 +69,7 @@ var AssetTableColumns = struct {
 	GlobalAssetID:   "asset.global_asset_id",
 	ProviderID:      "asset.provider_id",
 	AssetID:         "asset.asset_id",
+	Pinned:          "asset.pinned",
 }
 
 // Generated where
@@ -157,6 +162,15 @@ func (w whereHelpernull_Int32) NIN(slice []int32) qm.QueryMod {
 func (w whereHelpernull_Int32) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
 func (w whereHelpernull_Int32) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
 
+type whereHelperbool struct{ field string }
+
+func (w whereHelperbool) EQ(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
+func (w whereHelperbool) NEQ(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.NEQ, x) }
+func (w whereHelperbool) LT(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.LT, x) }
+func (w whereHelperbool) LTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LTE, x) }
+func (w whereHelperbool) GT(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
+func (w whereHelperbool) GTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
+
 var AssetWhere = struct {
 	ID              whereHelperint64
 	ConfigurationID whereHelperint64
@@ -164,6 +178,7 @@ var AssetWhere = struct {
 	GlobalAssetID   whereHelperstring
 	ProviderID      whereHelperstring
 	AssetID         whereHelpernull_Int32
+	Pinned          whereHelperbool
 }{
 	ID:              whereHelperint64{field: "\"mystrom\".\"asset\".\"id\""},
 	ConfigurationID: whereHelperint64{field: "\"mystrom\".\"asset\".\"configuration_id\""},
@@ -171,6 +186,7 @@ var AssetWhere = struct {
 	GlobalAssetID:   whereHelperstring{field: "\"mystrom\".\"asset\".\"global_asset_id\""},
 	ProviderID:      whereHelperstring{field: "\"mystrom\".\"asset\".\"provider_id\""},
 	AssetID:         whereHelpernull_Int32{field: "\"mystrom\".\"asset\".\"asset_id\""},
+	Pinned:          whereHelperbool{field: "\"mystrom\".\"asset\".\"pinned\""},
 }
 
 // AssetRels is where relationship names are stored.
@@ -201,9 +217,9 @@ func (r *assetR) GetConfiguration() *Configuration {
 type assetL struct{}
 
 var (
-	assetAllColumns            = []string{"id", "configuration_id", "project_id", "global_asset_id", "provider_id", "asset_id"}
+	assetAllColumns            = []string{"id", "configuration_id", "project_id", "global_asset_id", "provider_id", "asset_id", "pinned"}
 	assetColumnsWithoutDefault = []string{"project_id", "global_asset_id", "provider_id"}
-	assetColumnsWithDefault    = []string{"id", "configuration_id", "asset_id"}
+	assetColumnsWithDefault    = []string{"id", "configuration_id", "asset_id", "pinned"}
 	assetPrimaryKeyColumns     = []string{"id"}
 	assetGeneratedColumns      = []string{}
 )
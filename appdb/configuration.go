@@ -25,67 +25,177 @@ import (
 
 // Configuration is an object representing the database table.
 type Configuration struct {
-	ID               int64             `boil:"id" json:"id" toml:"id" yaml:"id"`
-	APIKey           string            `boil:"api_key" json:"api_key" toml:"api_key" yaml:"api_key"`
-	RefreshInterval  int32             `boil:"refresh_interval" json:"refresh_interval" toml:"refresh_interval" yaml:"refresh_interval"`
-	DataPollInterval int32             `boil:"data_poll_interval" json:"data_poll_interval" toml:"data_poll_interval" yaml:"data_poll_interval"`
-	RequestTimeout   int32             `boil:"request_timeout" json:"request_timeout" toml:"request_timeout" yaml:"request_timeout"`
-	AssetFilter      null.JSON         `boil:"asset_filter" json:"asset_filter,omitempty" toml:"asset_filter" yaml:"asset_filter,omitempty"`
-	Active           null.Bool         `boil:"active" json:"active,omitempty" toml:"active" yaml:"active,omitempty"`
-	Enable           null.Bool         `boil:"enable" json:"enable,omitempty" toml:"enable" yaml:"enable,omitempty"`
-	ProjectIds       types.StringArray `boil:"project_ids" json:"project_ids,omitempty" toml:"project_ids" yaml:"project_ids,omitempty"`
-	UserID           null.String       `boil:"user_id" json:"user_id,omitempty" toml:"user_id" yaml:"user_id,omitempty"`
+	ID                   int64             `boil:"id" json:"id" toml:"id" yaml:"id"`
+	APIKey               string            `boil:"api_key" json:"api_key" toml:"api_key" yaml:"api_key"`
+	RefreshInterval      int32             `boil:"refresh_interval" json:"refresh_interval" toml:"refresh_interval" yaml:"refresh_interval"`
+	DataPollInterval     int32             `boil:"data_poll_interval" json:"data_poll_interval" toml:"data_poll_interval" yaml:"data_poll_interval"`
+	RequestTimeout       int32             `boil:"request_timeout" json:"request_timeout" toml:"request_timeout" yaml:"request_timeout"`
+	AssetFilter          null.JSON         `boil:"asset_filter" json:"asset_filter,omitempty" toml:"asset_filter" yaml:"asset_filter,omitempty"`
+	Active               null.Bool         `boil:"active" json:"active,omitempty" toml:"active" yaml:"active,omitempty"`
+	Enable               null.Bool         `boil:"enable" json:"enable,omitempty" toml:"enable" yaml:"enable,omitempty"`
+	ProjectIds           types.StringArray `boil:"project_ids" json:"project_ids,omitempty" toml:"project_ids" yaml:"project_ids,omitempty"`
+	UserID               null.String       `boil:"user_id" json:"user_id,omitempty" toml:"user_id" yaml:"user_id,omitempty"`
+	IncludeDeviceIds     types.StringArray `boil:"include_device_ids" json:"include_device_ids,omitempty" toml:"include_device_ids" yaml:"include_device_ids,omitempty"`
+	ExcludeDeviceIds     types.StringArray `boil:"exclude_device_ids" json:"exclude_device_ids,omitempty" toml:"exclude_device_ids" yaml:"exclude_device_ids,omitempty"`
+	ParentAssets         null.JSON         `boil:"parent_assets" json:"parent_assets,omitempty" toml:"parent_assets" yaml:"parent_assets,omitempty"`
+	AssetNameTemplate    null.String       `boil:"asset_name_template" json:"asset_name_template,omitempty" toml:"asset_name_template" yaml:"asset_name_template,omitempty"`
+	RenameExistingAssets bool              `boil:"rename_existing_assets" json:"rename_existing_assets" toml:"rename_existing_assets" yaml:"rename_existing_assets"`
+	ProjectFilters       null.JSON         `boil:"project_filters" json:"project_filters,omitempty" toml:"project_filters" yaml:"project_filters,omitempty"`
+	LastDiscoveryAt      null.Time         `boil:"last_discovery_at" json:"last_discovery_at,omitempty" toml:"last_discovery_at" yaml:"last_discovery_at,omitempty"`
+	LastPollAt           null.Time         `boil:"last_poll_at" json:"last_poll_at,omitempty" toml:"last_poll_at" yaml:"last_poll_at,omitempty"`
+	LastError            null.String       `boil:"last_error" json:"last_error,omitempty" toml:"last_error" yaml:"last_error,omitempty"`
+	LastErrorAt          null.Time         `boil:"last_error_at" json:"last_error_at,omitempty" toml:"last_error_at" yaml:"last_error_at,omitempty"`
+	ConsecutiveFailures  int32             `boil:"consecutive_failures" json:"consecutive_failures" toml:"consecutive_failures" yaml:"consecutive_failures"`
+	DeviceCount          int32             `boil:"device_count" json:"device_count" toml:"device_count" yaml:"device_count"`
+	AssetCount           int32             `boil:"asset_count" json:"asset_count" toml:"asset_count" yaml:"asset_count"`
+	Suspended            bool              `boil:"suspended" json:"suspended" toml:"suspended" yaml:"suspended"`
+	AuthFailures         int32             `boil:"auth_failures" json:"auth_failures" toml:"auth_failures" yaml:"auth_failures"`
+	APIV1RateLimit       int32             `boil:"api_v1_rate_limit" json:"api_v1_rate_limit" toml:"api_v1_rate_limit" yaml:"api_v1_rate_limit"`
+	APIV2RateLimit       int32             `boil:"api_v2_rate_limit" json:"api_v2_rate_limit" toml:"api_v2_rate_limit" yaml:"api_v2_rate_limit"`
+	PowerDeadband        float32           `boil:"power_deadband" json:"power_deadband" toml:"power_deadband" yaml:"power_deadband"`
+	TemperatureDeadband  float32           `boil:"temperature_deadband" json:"temperature_deadband" toml:"temperature_deadband" yaml:"temperature_deadband"`
+	HeartbeatInterval    int32             `boil:"heartbeat_interval" json:"heartbeat_interval" toml:"heartbeat_interval" yaml:"heartbeat_interval"`
+	BufferMaxReadings    int32             `boil:"buffer_max_readings" json:"buffer_max_readings" toml:"buffer_max_readings" yaml:"buffer_max_readings"`
+	BufferMaxAge         int32             `boil:"buffer_max_age" json:"buffer_max_age" toml:"buffer_max_age" yaml:"buffer_max_age"`
 
 	R *configurationR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L configurationL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var ConfigurationColumns = struct {
-	ID               string
-	APIKey           string
-	RefreshInterval  string
-	DataPollInterval string
-	RequestTimeout   string
-	AssetFilter      string
-	Active           string
-	Enable           string
-	ProjectIds       string
-	UserID           string
+	ID                   string
+	APIKey               string
+	RefreshInterval      string
+	DataPollInterval     string
+	RequestTimeout       string
+	AssetFilter          string
+	Active               string
+	Enable               string
+	ProjectIds           string
+	UserID               string
+	IncludeDeviceIds     string
+	ExcludeDeviceIds     string
+	ParentAssets         string
+	AssetNameTemplate    string
+	RenameExistingAssets string
+	ProjectFilters       string
+	LastDiscoveryAt      string
+	LastPollAt           string
+	LastError            string
+	LastErrorAt          string
+	ConsecutiveFailures  string
+	DeviceCount          string
+	AssetCount           string
+	Suspended            string
+	AuthFailures         string
+	APIV1RateLimit       string
+	APIV2RateLimit       string
+	PowerDeadband        string
+	TemperatureDeadband  string
+	HeartbeatInterval    string
+	BufferMaxReadings    string
+	BufferMaxAge         string
 }{
-	ID:               "id",
-	APIKey:           "api_key",
-	RefreshInterval:  "refresh_interval",
-	DataPollInterval: "data_poll_interval",
-	RequestTimeout:   "request_timeout",
-	AssetFilter:      "asset_filter",
-	Active:           "active",
-	Enable:           "enable",
-	ProjectIds:       "project_ids",
-	UserID:           "user_id",
+	ID:                   "id",
+	APIKey:               "api_key",
+	RefreshInterval:      "refresh_interval",
+	DataPollInterval:     "data_poll_interval",
+	RequestTimeout:       "request_timeout",
+	AssetFilter:          "asset_filter",
+	Active:               "active",
+	Enable:               "enable",
+	ProjectIds:           "project_ids",
+	UserID:               "user_id",
+	IncludeDeviceIds:     "include_device_ids",
+	ExcludeDeviceIds:     "exclude_device_ids",
+	ParentAssets:         "parent_assets",
+	AssetNameTemplate:    "asset_name_template",
+	RenameExistingAssets: "rename_existing_assets",
+	ProjectFilters:       "project_filters",
+	LastDiscoveryAt:      "last_discovery_at",
+	LastPollAt:           "last_poll_at",
+	LastError:            "last_error",
+	LastErrorAt:          "last_error_at",
+	ConsecutiveFailures:  "consecutive_failures",
+	DeviceCount:          "device_count",
+	AssetCount:           "asset_count",
+	Suspended:            "suspended",
+	AuthFailures:         "auth_failures",
+	APIV1RateLimit:       "api_v1_rate_limit",
+	APIV2RateLimit:       "api_v2_rate_limit",
+	PowerDeadband:        "power_deadband",
+	TemperatureDeadband:  "temperature_deadband",
+	HeartbeatInterval:    "heartbeat_interval",
+	BufferMaxReadings:    "buffer_max_readings",
+	BufferMaxAge:         "buffer_max_age",
 }
 
 var ConfigurationTableColumns = struct {
-	ID               string
-	APIKey           string
-	RefreshInterval  string
-	DataPollInterval string
-	RequestTimeout   string
-	AssetFilter      string
-	Active           string
-	Enable           string
-	ProjectIds       string
-	UserID           string
+	ID                   string
+	APIKey               string
+	RefreshInterval      string
+	DataPollInterval     string
+	RequestTimeout       string
+	AssetFilter          string
+	Active               string
+	Enable               string
+	ProjectIds           string
+	UserID               string
+	IncludeDeviceIds     string
+	ExcludeDeviceIds     string
+	ParentAssets         string
+	AssetNameTemplate    string
+	RenameExistingAssets string
+	ProjectFilters       string
+	LastDiscoveryAt      string
+	LastPollAt           string
+	LastError            string
+	LastErrorAt          string
+	ConsecutiveFailures  string
+	DeviceCount          string
+	AssetCount           string
+	Suspended            string
+	AuthFailures         string
+	APIV1RateLimit       string
+	APIV2RateLimit       string
+	PowerDeadband        string
+	TemperatureDeadband  string
+	HeartbeatInterval    string
+	BufferMaxReadings    string
+	BufferMaxAge         string
 }{
-	ID:               "configuration.id",
-	APIKey:           "configuration.api_key",
-	RefreshInterval:  "configuration.refresh_interval",
-	DataPollInterval: "configuration.data_poll_interval",
-	RequestTimeout:   "configuration.request_timeout",
-	AssetFilter:      "configuration.asset_filter",
-	Active:           "configuration.active",
-	Enable:           "configuration.enable",
-	ProjectIds:       "configuration.project_ids",
-	UserID:           "configuration.user_id",
+	ID:                   "configuration.id",
+	APIKey:               "configuration.api_key",
+	RefreshInterval:      "configuration.refresh_interval",
+	DataPollInterval:     "configuration.data_poll_interval",
+	RequestTimeout:       "configuration.request_timeout",
+	AssetFilter:          "configuration.asset_filter",
+	Active:               "configuration.active",
+	Enable:               "configuration.enable",
+	ProjectIds:           "configuration.project_ids",
+	UserID:               "configuration.user_id",
+	IncludeDeviceIds:     "configuration.include_device_ids",
+	ExcludeDeviceIds:     "configuration.exclude_device_ids",
+	ParentAssets:         "configuration.parent_assets",
+	AssetNameTemplate:    "configuration.asset_name_template",
+	RenameExistingAssets: "configuration.rename_existing_assets",
+	ProjectFilters:       "configuration.project_filters",
+	LastDiscoveryAt:      "configuration.last_discovery_at",
+	LastPollAt:           "configuration.last_poll_at",
+	LastError:            "configuration.last_error",
+	LastErrorAt:          "configuration.last_error_at",
+	ConsecutiveFailures:  "configuration.consecutive_failures",
+	DeviceCount:          "configuration.device_count",
+	AssetCount:           "configuration.asset_count",
+	Suspended:            "configuration.suspended",
+	AuthFailures:         "configuration.auth_failures",
+	APIV1RateLimit:       "configuration.api_v1_rate_limit",
+	APIV2RateLimit:       "configuration.api_v2_rate_limit",
+	PowerDeadband:        "configuration.power_deadband",
+	TemperatureDeadband:  "configuration.temperature_deadband",
+	HeartbeatInterval:    "configuration.heartbeat_interval",
+	BufferMaxReadings:    "configuration.buffer_max_readings",
+	BufferMaxAge:         "configuration.buffer_max_age",
 }
 
 // Generated where
@@ -113,6 +223,35 @@ func (w whereHelperint32) NIN(slice []int32) qm.QueryMod {
 	return qm.WhereNotIn(fmt.Sprintf("%s NOT IN ?", w.field), values...)
 }
 
+type whereHelperfloat32 struct{ field string }
+
+func (w whereHelperfloat32) EQ(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.EQ, x) }
+func (w whereHelperfloat32) NEQ(x float32) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.NEQ, x)
+}
+func (w whereHelperfloat32) LT(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LT, x) }
+func (w whereHelperfloat32) LTE(x float32) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelperfloat32) GT(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GT, x) }
+func (w whereHelperfloat32) GTE(x float32) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+func (w whereHelperfloat32) IN(slice []float32) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
+}
+func (w whereHelperfloat32) NIN(slice []float32) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereNotIn(fmt.Sprintf("%s NOT IN ?", w.field), values...)
+}
+
 type whereHelpernull_JSON struct{ field string }
 
 func (w whereHelpernull_JSON) EQ(x null.JSON) qm.QueryMod {
@@ -237,28 +376,96 @@ func (w whereHelpernull_String) NIN(slice []string) qm.QueryMod {
 func (w whereHelpernull_String) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
 func (w whereHelpernull_String) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
 
+type whereHelpernull_Time struct{ field string }
+
+func (w whereHelpernull_Time) EQ(x null.Time) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpernull_Time) NEQ(x null.Time) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpernull_Time) LT(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpernull_Time) LTE(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpernull_Time) GT(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpernull_Time) GTE(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+
+func (w whereHelpernull_Time) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpernull_Time) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+
 var ConfigurationWhere = struct {
-	ID               whereHelperint64
-	APIKey           whereHelperstring
-	RefreshInterval  whereHelperint32
-	DataPollInterval whereHelperint32
-	RequestTimeout   whereHelperint32
-	AssetFilter      whereHelpernull_JSON
-	Active           whereHelpernull_Bool
-	Enable           whereHelpernull_Bool
-	ProjectIds       whereHelpertypes_StringArray
-	UserID           whereHelpernull_String
+	ID                   whereHelperint64
+	APIKey               whereHelperstring
+	RefreshInterval      whereHelperint32
+	DataPollInterval     whereHelperint32
+	RequestTimeout       whereHelperint32
+	AssetFilter          whereHelpernull_JSON
+	Active               whereHelpernull_Bool
+	Enable               whereHelpernull_Bool
+	ProjectIds           whereHelpertypes_StringArray
+	UserID               whereHelpernull_String
+	IncludeDeviceIds     whereHelpertypes_StringArray
+	ExcludeDeviceIds     whereHelpertypes_StringArray
+	ParentAssets         whereHelpernull_JSON
+	AssetNameTemplate    whereHelpernull_String
+	RenameExistingAssets whereHelperbool
+	ProjectFilters       whereHelpernull_JSON
+	LastDiscoveryAt      whereHelpernull_Time
+	LastPollAt           whereHelpernull_Time
+	LastError            whereHelpernull_String
+	LastErrorAt          whereHelpernull_Time
+	ConsecutiveFailures  whereHelperint32
+	DeviceCount          whereHelperint32
+	AssetCount           whereHelperint32
+	Suspended            whereHelperbool
+	AuthFailures         whereHelperint32
+	APIV1RateLimit       whereHelperint32
+	APIV2RateLimit       whereHelperint32
+	PowerDeadband        whereHelperfloat32
+	TemperatureDeadband  whereHelperfloat32
+	HeartbeatInterval    whereHelperint32
+	BufferMaxReadings    whereHelperint32
+	BufferMaxAge         whereHelperint32
 }{
-	ID:               whereHelperint64{field: "\"mystrom\".\"configuration\".\"id\""},
-	APIKey:           whereHelperstring{field: "\"mystrom\".\"configuration\".\"api_key\""},
-	RefreshInterval:  whereHelperint32{field: "\"mystrom\".\"configuration\".\"refresh_interval\""},
-	DataPollInterval: whereHelperint32{field: "\"mystrom\".\"configuration\".\"data_poll_interval\""},
-	RequestTimeout:   whereHelperint32{field: "\"mystrom\".\"configuration\".\"request_timeout\""},
-	AssetFilter:      whereHelpernull_JSON{field: "\"mystrom\".\"configuration\".\"asset_filter\""},
-	Active:           whereHelpernull_Bool{field: "\"mystrom\".\"configuration\".\"active\""},
-	Enable:           whereHelpernull_Bool{field: "\"mystrom\".\"configuration\".\"enable\""},
-	ProjectIds:       whereHelpertypes_StringArray{field: "\"mystrom\".\"configuration\".\"project_ids\""},
-	UserID:           whereHelpernull_String{field: "\"mystrom\".\"configuration\".\"user_id\""},
+	ID:                   whereHelperint64{field: "\"mystrom\".\"configuration\".\"id\""},
+	APIKey:               whereHelperstring{field: "\"mystrom\".\"configuration\".\"api_key\""},
+	RefreshInterval:      whereHelperint32{field: "\"mystrom\".\"configuration\".\"refresh_interval\""},
+	DataPollInterval:     whereHelperint32{field: "\"mystrom\".\"configuration\".\"data_poll_interval\""},
+	RequestTimeout:       whereHelperint32{field: "\"mystrom\".\"configuration\".\"request_timeout\""},
+	AssetFilter:          whereHelpernull_JSON{field: "\"mystrom\".\"configuration\".\"asset_filter\""},
+	Active:               whereHelpernull_Bool{field: "\"mystrom\".\"configuration\".\"active\""},
+	Enable:               whereHelpernull_Bool{field: "\"mystrom\".\"configuration\".\"enable\""},
+	ProjectIds:           whereHelpertypes_StringArray{field: "\"mystrom\".\"configuration\".\"project_ids\""},
+	UserID:               whereHelpernull_String{field: "\"mystrom\".\"configuration\".\"user_id\""},
+	IncludeDeviceIds:     whereHelpertypes_StringArray{field: "\"mystrom\".\"configuration\".\"include_device_ids\""},
+	ExcludeDeviceIds:     whereHelpertypes_StringArray{field: "\"mystrom\".\"configuration\".\"exclude_device_ids\""},
+	ParentAssets:         whereHelpernull_JSON{field: "\"mystrom\".\"configuration\".\"parent_assets\""},
+	AssetNameTemplate:    whereHelpernull_String{field: "\"mystrom\".\"configuration\".\"asset_name_template\""},
+	RenameExistingAssets: whereHelperbool{field: "\"mystrom\".\"configuration\".\"rename_existing_assets\""},
+	ProjectFilters:       whereHelpernull_JSON{field: "\"mystrom\".\"configuration\".\"project_filters\""},
+	LastDiscoveryAt:      whereHelpernull_Time{field: "\"mystrom\".\"configuration\".\"last_discovery_at\""},
+	LastPollAt:           whereHelpernull_Time{field: "\"mystrom\".\"configuration\".\"last_poll_at\""},
+	LastError:            whereHelpernull_String{field: "\"mystrom\".\"configuration\".\"last_error\""},
+	LastErrorAt:          whereHelpernull_Time{field: "\"mystrom\".\"configuration\".\"last_error_at\""},
+	ConsecutiveFailures:  whereHelperint32{field: "\"mystrom\".\"configuration\".\"consecutive_failures\""},
+	DeviceCount:          whereHelperint32{field: "\"mystrom\".\"configuration\".\"device_count\""},
+	AssetCount:           whereHelperint32{field: "\"mystrom\".\"configuration\".\"asset_count\""},
+	Suspended:            whereHelperbool{field: "\"mystrom\".\"configuration\".\"suspended\""},
+	AuthFailures:         whereHelperint32{field: "\"mystrom\".\"configuration\".\"auth_failures\""},
+	APIV1RateLimit:       whereHelperint32{field: "\"mystrom\".\"configuration\".\"api_v1_rate_limit\""},
+	APIV2RateLimit:       whereHelperint32{field: "\"mystrom\".\"configuration\".\"api_v2_rate_limit\""},
+	PowerDeadband:        whereHelperfloat32{field: "\"mystrom\".\"configuration\".\"power_deadband\""},
+	TemperatureDeadband:  whereHelperfloat32{field: "\"mystrom\".\"configuration\".\"temperature_deadband\""},
+	HeartbeatInterval:    whereHelperint32{field: "\"mystrom\".\"configuration\".\"heartbeat_interval\""},
+	BufferMaxReadings:    whereHelperint32{field: "\"mystrom\".\"configuration\".\"buffer_max_readings\""},
+	BufferMaxAge:         whereHelperint32{field: "\"mystrom\".\"configuration\".\"buffer_max_age\""},
 }
 
 // ConfigurationRels is where relationship names are stored.
@@ -289,9 +496,9 @@ func (r *configurationR) GetAssets() AssetSlice {
 type configurationL struct{}
 
 var (
-	configurationAllColumns            = []string{"id", "api_key", "refresh_interval", "data_poll_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id"}
+	configurationAllColumns            = []string{"id", "api_key", "refresh_interval", "data_poll_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id", "include_device_ids", "exclude_device_ids", "parent_assets", "asset_name_template", "rename_existing_assets", "project_filters", "last_discovery_at", "last_poll_at", "last_error", "last_error_at", "consecutive_failures", "device_count", "asset_count", "suspended", "auth_failures", "api_v1_rate_limit", "api_v2_rate_limit", "power_deadband", "temperature_deadband", "heartbeat_interval", "buffer_max_readings", "buffer_max_age"}
 	configurationColumnsWithoutDefault = []string{"api_key"}
-	configurationColumnsWithDefault    = []string{"id", "refresh_interval", "data_poll_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id"}
+	configurationColumnsWithDefault    = []string{"id", "refresh_interval", "data_poll_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id", "include_device_ids", "exclude_device_ids", "parent_assets", "asset_name_template", "rename_existing_assets", "project_filters", "last_discovery_at", "last_poll_at", "last_error", "last_error_at", "consecutive_failures", "device_count", "asset_count", "suspended", "auth_failures", "api_v1_rate_limit", "api_v2_rate_limit", "power_deadband", "temperature_deadband", "heartbeat_interval", "buffer_max_readings", "buffer_max_age"}
 	configurationPrimaryKeyColumns     = []string{"id"}
 	configurationGeneratedColumns      = []string{}
 )
@@ -16,11 +16,15 @@
 package broker
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"mystrom/apiserver"
+	"mystrom/metrics"
 	"mystrom/model"
 	nethttp "net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
@@ -28,32 +32,120 @@ import (
 	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
+// ErrUnauthorized is returned when myStrom rejects the API key of the configuration.
+var ErrUnauthorized = errors.New("API key rejected")
+
+// unauthorized reports an error wrapping ErrUnauthorized if the status code means that the API key
+// was rejected.
+func unauthorized(statusCode int) error {
+	if statusCode == nethttp.StatusUnauthorized || statusCode == nethttp.StatusForbidden {
+		return fmt.Errorf("%w: got status %v", ErrUnauthorized, statusCode)
+	}
+	return nil
+}
+
+type deviceV1 struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Power          float32 `json:"power"`
+	WifiSwitchTemp float32 `json:"wifiSwitchTemp"`
+	State          string  `json:"state"`
+	Type           string  `json:"type"`
+	Room           struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"room"`
+}
+
 type devicesResponse struct {
-	Devices []struct {
-		ID             string  `json:"id"`
-		Name           string  `json:"name"`
-		Power          float32 `json:"power"`
-		WifiSwitchTemp float32 `json:"wifiSwitchTemp"`
-		State          string  `json:"state"`
-		Type           string  `json:"type"`
-		Room           struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
-		} `json:"room"`
-	} `json:"devices"`
-	Status string `json:"status"`
+	Devices []deviceV1 `json:"devices"`
+	Status  string     `json:"status"`
+
+	// receivedAt is when the response arrived. myStrom does not report when the values were
+	// measured, so this is the closest measurement time available.
+	receivedAt time.Time
+}
+
+// Device lists are shared between configurations using the same myStrom account.
+var (
+	devicesCache   = newResponseCache[devicesResponse](metrics.EndpointDevicesV1)
+	devicesV2Cache = newResponseCache[devicesResponseV2](metrics.EndpointDevicesV2)
+)
+
+// cachedDevices returns the device list of API v1 for the account of the configuration, reusing a
+// recent response fetched for another configuration.
+func cachedDevices(ctx context.Context, config apiserver.Configuration) (devicesResponse, int, error) {
+	return devicesCache.get(ctx, config.ApiKey, func(ctx context.Context) (devicesResponse, int, error) {
+		return requestDevices(ctx, config)
+	})
 }
 
-func GetDevices(config apiserver.Configuration) (model.Root, error) {
+func requestDevices(ctx context.Context, config apiserver.Configuration) (devicesResponse, int, error) {
 	// API v1 is called here for the rooms list. Be careful not to overuse it, though. No frequent
 	// polling should be done to api v1.
 	req, err := http.NewRequestWithApiKey("https://mystrom.ch/api/devices", "Auth-Token", config.ApiKey)
 	if err != nil {
-		return model.Root{}, fmt.Errorf("creating request for devices: %v", err)
+		return devicesResponse{}, 0, fmt.Errorf("creating request for devices: %v", err)
+	}
+	resp, statusCode, err := request(ctx, apiV1Account(ctx, config), func(ctx context.Context) (devicesResponse, int, error) {
+		start := time.Now()
+		resp, statusCode, err := http.ReadWithStatusCode[devicesResponse](req.WithContext(ctx), time.Duration(*config.RequestTimeout)*time.Second, true)
+		metrics.ObserveRequest(metrics.EndpointDevicesV1, config.Id, statusCode, time.Since(start))
+		resp.receivedAt = time.Now()
+		return resp, statusCode, err
+	})
+	if err := unauthorized(statusCode); err != nil {
+		return devicesResponse{}, statusCode, fmt.Errorf("querying API for devices: %w", err)
 	}
-	resp, statusCode, err := http.ReadWithStatusCode[devicesResponse](req, time.Duration(*config.RequestTimeout)*time.Second, true)
 	if err != nil {
-		return model.Root{}, fmt.Errorf("querying API for devices: %v", err)
+		return devicesResponse{}, statusCode, fmt.Errorf("querying API for devices: %w", err)
+	}
+	return resp, statusCode, nil
+}
+
+// deviceFromV1 converts a device reported by API v1 at the given time. Returns false for
+// unsupported device types.
+func deviceFromV1(ctx context.Context, d deviceV1, measuredAt time.Time, config *apiserver.Configuration) (model.Device, bool) {
+	relayState := 0
+	if d.State == "on" {
+		relayState = 1
+	}
+	switch d.Type {
+	case "ws2", "wse":
+		return &model.Switch{
+			ID:         d.ID,
+			Name:       d.Name,
+			Type:       d.Type,
+			RoomID:     d.Room.ID,
+			RoomName:   d.Room.Name,
+			Power:      d.Power,
+			Temp:       d.WifiSwitchTemp,
+			Relay:      relayState,
+			MeasuredAt: measuredAt,
+			Config:     config,
+			Context:    ctx,
+		}, true
+	case "lcs":
+		return &model.SwitchZero{
+			ID:         d.ID,
+			Name:       d.Name,
+			Type:       d.Type,
+			RoomID:     d.Room.ID,
+			RoomName:   d.Room.Name,
+			Relay:      relayState,
+			MeasuredAt: measuredAt,
+			Config:     config,
+			Context:    ctx,
+		}, true
+	default:
+		return nil, false // We suport only WS2, WSE and LCS smart plugs.
+	}
+}
+
+func GetDevices(ctx context.Context, config apiserver.Configuration) (model.Root, error) {
+	resp, statusCode, err := cachedDevices(ctx, config)
+	if err != nil {
+		return model.Root{}, err
 	}
 	if statusCode != nethttp.StatusOK {
 		return model.Root{}, fmt.Errorf("querying API for devices: got status %v", statusCode)
@@ -63,76 +155,91 @@ func GetDevices(config apiserver.Configuration) (model.Root, error) {
 	}
 
 	root := model.Root{
-		Rooms:  make(map[string]model.Room),
-		Config: &config,
+		Rooms:   make(map[string]model.Room),
+		Config:  &config,
+		Context: ctx,
 	}
+	byType := make(map[string]int)
 	for _, d := range resp.Devices {
-		switch d.Type {
-		case "ws2", "wse":
-			relayState := 0
-			if d.State == "on" {
-				relayState = 1
-			}
-			s := model.Switch{
-				ID:     d.ID,
-				Name:   d.Name,
-				Power:  d.Power,
-				Temp:   d.WifiSwitchTemp,
-				Relay:  relayState,
-				Config: &config,
-			}
-			if adheres, err := s.AdheresToFilter(config.AssetFilter); err != nil {
-				return model.Root{}, fmt.Errorf("checking if adheres to filter: %v", err)
-			} else if !adheres {
-				continue
-			}
-			root.Switches = append(root.Switches, &s)
-			r, ok := root.Rooms[d.Room.ID]
-			if !ok {
-				r = model.Room{
-					ID:       d.Room.ID,
-					Name:     d.Room.Name,
-					Switches: []asset.LocationalNode{},
-					Config:   &config,
-				}
-			}
-			r.Switches = append(r.Switches, &s)
-			root.Rooms[d.Room.ID] = r
-		case "lcs":
-			relayState := 0
-			if d.State == "on" {
-				relayState = 1
-			}
-			s := model.SwitchZero{
-				ID:     d.ID,
-				Name:   d.Name,
-				Relay:  relayState,
-				Config: &config,
-			}
-			if adheres, err := s.AdheresToFilter(config.AssetFilter); err != nil {
-				return model.Root{}, fmt.Errorf("checking if adheres to filter: %v", err)
-			} else if !adheres {
-				continue
-			}
-			root.Switches = append(root.Switches, &s)
-			r, ok := root.Rooms[d.Room.ID]
-			if !ok {
-				r = model.Room{
-					ID:       d.Room.ID,
-					Name:     d.Room.Name,
-					Switches: []asset.LocationalNode{},
-					Config:   &config,
-				}
+		byType[d.Type]++
+	}
+	metrics.SetDevices(*config.Id, byType)
+	for _, d := range resp.Devices {
+		s, ok := deviceFromV1(ctx, d, resp.receivedAt, &config)
+		if !ok {
+			continue
+		}
+		if adheres, err := s.AdheresToFilter(config); err != nil {
+			return model.Root{}, fmt.Errorf("checking if adheres to filter: %v", err)
+		} else if !adheres {
+			root.Excluded = append(root.Excluded, s)
+			continue
+		}
+		root.Switches = append(root.Switches, s)
+		r, ok := root.Rooms[d.Room.ID]
+		if !ok {
+			r = model.Room{
+				ID:       d.Room.ID,
+				Name:     d.Room.Name,
+				Switches: []asset.LocationalNode{},
+				Config:   &config,
+				Context:  ctx,
 			}
-			r.Switches = append(r.Switches, &s)
-			root.Rooms[d.Room.ID] = r
-		default:
-			continue // We suport only WS2, WSE and LCS smart plugs.
 		}
+		r.Switches = append(r.Switches, s)
+		root.Rooms[d.Room.ID] = r
 	}
 	return root, nil
 }
 
+// ConnectionTest is the outcome of probing the myStrom API with a configuration.
+type ConnectionTest struct {
+	Authenticated        bool
+	StatusCode           int
+	Latency              time.Duration
+	DevicesByType        map[string]int
+	DevicesPassingFilter int
+}
+
+// TestConnection queries the device list once to verify the API key of the configuration. The
+// devices are only counted, no assets are created. A cached response is never used, so that the
+// reported latency is the one of a real request.
+func TestConnection(ctx context.Context, config apiserver.Configuration) (ConnectionTest, error) {
+	start := time.Now()
+	resp, statusCode, err := requestDevices(ctx, config)
+	result := ConnectionTest{
+		StatusCode:    statusCode,
+		Latency:       time.Since(start),
+		DevicesByType: make(map[string]int),
+	}
+	if err != nil {
+		return result, err
+	}
+	if statusCode != nethttp.StatusOK {
+		return result, fmt.Errorf("querying API for devices: got status %v", statusCode)
+	}
+	result.Authenticated = true
+	if resp.Status != "ok" {
+		return result, fmt.Errorf("API reports non-ok status: %v", resp.Status)
+	}
+
+	for _, d := range resp.Devices {
+		result.DevicesByType[d.Type]++
+		s, ok := deviceFromV1(ctx, d, resp.receivedAt, &config)
+		if !ok {
+			continue
+		}
+		adheres, err := s.AdheresToFilter(config)
+		if err != nil {
+			return result, fmt.Errorf("checking if adheres to filter: %v", err)
+		}
+		if adheres {
+			result.DevicesPassingFilter++
+		}
+	}
+	return result, nil
+}
+
 type devicesResponseV2 struct {
 	Devices []struct {
 		ID          string  `json:"id"`
@@ -142,18 +249,38 @@ type devicesResponseV2 struct {
 		Temperature float32 `json:"temperature"`
 		Type        string  `json:"type"`
 	} `json:"devices"`
+
+	// receivedAt is when the response arrived, see devicesResponse.
+	receivedAt time.Time
 }
 
-func GetData(config apiserver.Configuration) ([]asset.Asset, error) {
+func requestDevicesV2(ctx context.Context, config apiserver.Configuration) (devicesResponseV2, int, error) {
 	// API v2 should be the preferred choice when communicating with myStrom. But ideally the
 	// fetching of data should be done using webhooks.
 	r, err := http.NewRequestWithApiKey("https://mystrom.ch/api/v2/devices", "Auth-Token", config.ApiKey)
 	if err != nil {
-		return nil, fmt.Errorf("creating request for devices: %v", err)
+		return devicesResponseV2{}, 0, fmt.Errorf("creating request for devices: %v", err)
+	}
+	return request(ctx, apiV2Account(config), func(ctx context.Context) (devicesResponseV2, int, error) {
+		start := time.Now()
+		resp, statusCode, err := http.ReadWithStatusCode[devicesResponseV2](r.WithContext(ctx), time.Duration(*config.RequestTimeout)*time.Second, true)
+		metrics.ObserveRequest(metrics.EndpointDevicesV2, config.Id, statusCode, time.Since(start))
+		resp.receivedAt = time.Now()
+		return resp, statusCode, err
+	})
+}
+
+// GetData fetches the current data of all devices of the account. A response fetched for another
+// configuration with the same API key within the last seconds is reused.
+func GetData(ctx context.Context, config apiserver.Configuration) ([]asset.Asset, error) {
+	resp, statusCode, err := devicesV2Cache.get(ctx, config.ApiKey, func(ctx context.Context) (devicesResponseV2, int, error) {
+		return requestDevicesV2(ctx, config)
+	})
+	if err := unauthorized(statusCode); err != nil {
+		return nil, fmt.Errorf("querying API for devices: %w", err)
 	}
-	resp, statusCode, err := http.ReadWithStatusCode[devicesResponseV2](r, time.Duration(*config.RequestTimeout)*time.Second, true)
 	if err != nil {
-		return nil, fmt.Errorf("querying API for devices: %v", err)
+		return nil, fmt.Errorf("querying API for devices: %w", err)
 	}
 	if statusCode != nethttp.StatusOK {
 		return nil, fmt.Errorf("querying API for devices: got status %v", statusCode)
@@ -169,11 +296,15 @@ func GetData(config apiserver.Configuration) ([]asset.Asset, error) {
 				relayState = 1
 			}
 			switches = append(switches, &model.Switch{
-				ID:    device.ID,
-				Name:  device.Name,
-				Power: device.Power,
-				Temp:  device.Temperature,
-				Relay: relayState,
+				ID:         device.ID,
+				Name:       device.Name,
+				Type:       strings.ToLower(device.Type),
+				Power:      device.Power,
+				Temp:       device.Temperature,
+				Relay:      relayState,
+				MeasuredAt: resp.receivedAt,
+				Config:     &config,
+				Context:    ctx,
 			})
 		case "LCS":
 			relayState := 0
@@ -181,9 +312,13 @@ func GetData(config apiserver.Configuration) ([]asset.Asset, error) {
 				relayState = 1
 			}
 			switches = append(switches, &model.SwitchZero{
-				ID:    device.ID,
-				Name:  device.Name,
-				Relay: relayState,
+				ID:         device.ID,
+				Name:       device.Name,
+				Type:       strings.ToLower(device.Type),
+				Relay:      relayState,
+				MeasuredAt: resp.receivedAt,
+				Config:     &config,
+				Context:    ctx,
 			})
 		default:
 			// We suport only WS2, WSE and LCS smart plugs.
@@ -194,7 +329,7 @@ func GetData(config apiserver.Configuration) ([]asset.Asset, error) {
 	return switches, nil
 }
 
-func PostData(config apiserver.Configuration, deviceID string, value int64) error {
+func PostData(ctx context.Context, config apiserver.Configuration, deviceID string, value int64) error {
 	u, err := url.Parse(fmt.Sprintf("https://mystrom.ch/api/v2/device/%s", deviceID))
 	if err != nil {
 		return fmt.Errorf("shouldn't happen: parsing URL: %v", err)
@@ -206,18 +341,30 @@ func PostData(config apiserver.Configuration, deviceID string, value int64) erro
 	}
 	q.Set("action", action)
 	u.RawQuery = q.Encode()
-	var body interface{}
-	r, err := http.NewPostRequestWithApiKey(u.String(), body, "Auth-Token", config.ApiKey)
-	if err != nil {
-		return fmt.Errorf("creating request for devices: %v", err)
+	resp, statusCode, err := request(ctx, apiV2Account(config), func(ctx context.Context) ([]byte, int, error) {
+		// A new request for each attempt, as the body is consumed by the previous one.
+		var body interface{}
+		r, err := http.NewPostRequestWithApiKey(u.String(), body, "Auth-Token", config.ApiKey)
+		if err != nil {
+			return nil, 0, fmt.Errorf("creating request for devices: %v", err)
+		}
+		start := time.Now()
+		resp, statusCode, err := http.DoWithStatusCode(r.WithContext(ctx), time.Duration(*config.RequestTimeout)*time.Second, true)
+		metrics.ObserveRequest(metrics.EndpointDeviceV2Action, config.Id, statusCode, time.Since(start))
+		return resp, statusCode, err
+	})
+	if err := unauthorized(statusCode); err != nil {
+		return fmt.Errorf("posting action to device: %w", err)
 	}
-	resp, statusCode, err := http.DoWithStatusCode(r, time.Duration(*config.RequestTimeout)*time.Second, true)
 	if err != nil {
 		return fmt.Errorf("querying API for devices: %v", err)
 	}
 	if statusCode != nethttp.StatusOK {
 		return fmt.Errorf("querying API for devices: got status %v and response %s", statusCode, resp)
 	}
+	// The next poll has to see the new relay state.
+	devicesCache.invalidate(config.ApiKey)
+	devicesV2Cache.invalidate(config.ApiKey)
 	log.Debug("broker", "posted action %v to device %v", action, deviceID)
 	return nil
 }
@@ -0,0 +1,148 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package broker
+
+import (
+	"context"
+	"mystrom/metrics"
+	nethttp "net/http"
+	"sync"
+	"time"
+)
+
+// cacheTTL is how long a device list fetched from myStrom is reused by other configurations of
+// the same account. The scheduler polls all configurations of an account with the same poll
+// interval at the same moments, so their requests meet here; the TTL only has to cover requests
+// delayed by the rate limit or a slow run. It is far below any sensible poll interval, so the data
+// of a single configuration is never served twice.
+const cacheTTL = 10 * time.Second
+
+// responseCache shares the responses of one myStrom endpoint between configurations using the
+// same API key. Concurrent requests wait for a single upstream request, and successful responses
+// are reused for cacheTTL. Interactive and scheduled requests do not wait for each other, as they
+// handle the rate limit differently, but reuse each other's responses.
+type responseCache[T any] struct {
+	endpoint string
+
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry[T]
+}
+
+type cacheKey struct {
+	apiKey      string
+	interactive bool
+}
+
+type cacheEntry[T any] struct {
+	done       chan struct{}
+	cancel     context.CancelFunc
+	waiters    int
+	resp       T
+	statusCode int
+	err        error
+	fetchedAt  time.Time
+}
+
+func newResponseCache[T any](endpoint string) *responseCache[T] {
+	return &responseCache[T]{endpoint: endpoint, entries: make(map[cacheKey]*cacheEntry[T])}
+}
+
+// get returns the cached response for the API key or fetches it. The upstream request is
+// cancelled only when all callers waiting for it gave up.
+func (c *responseCache[T]) get(ctx context.Context, apiKey string, fetch func(context.Context) (T, int, error)) (T, int, error) {
+	key := cacheKey{apiKey: apiKey, interactive: isInteractive(ctx)}
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && e.expired() {
+		ok = false
+	}
+	if !ok {
+		// A response fetched in the other mode is as good, but a request in progress is not
+		// joined.
+		other := cacheKey{apiKey: apiKey, interactive: !key.interactive}
+		e, ok = c.entries[other]
+		ok = ok && e.finished() && !e.expired()
+	}
+	if ok {
+		metrics.CacheHit(c.endpoint)
+	} else {
+		e = c.fetch(ctx, key, fetch)
+	}
+	e.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.resp, e.statusCode, e.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		e.waiters--
+		if e.waiters == 0 && !e.finished() {
+			e.cancel()
+			if c.entries[key] == e {
+				delete(c.entries, key)
+			}
+		}
+		c.mu.Unlock()
+		var zero T
+		return zero, 0, ctx.Err()
+	}
+}
+
+// fetch starts the upstream request. It keeps the values of the caller's context, e.g. whether
+// the request is interactive, but not its cancellation. Must be called with c.mu held.
+func (c *responseCache[T]) fetch(ctx context.Context, key cacheKey, fetch func(context.Context) (T, int, error)) *cacheEntry[T] {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	e := &cacheEntry[T]{done: make(chan struct{}), cancel: cancel}
+	c.entries[key] = e
+	go func() {
+		defer cancel()
+		resp, statusCode, err := fetch(ctx)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		e.resp, e.statusCode, e.err, e.fetchedAt = resp, statusCode, err, time.Now()
+		if (err != nil || statusCode != nethttp.StatusOK) && c.entries[key] == e {
+			// Failures are shared with the callers waiting for them, but not reused.
+			delete(c.entries, key)
+		}
+		close(e.done)
+	}()
+	return e
+}
+
+// invalidate drops the cached response for the API key, e.g. after a device was switched. A
+// request in progress is left to its callers, but later ones do not join it.
+func (c *responseCache[T]) invalidate(apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, cacheKey{apiKey: apiKey, interactive: false})
+	delete(c.entries, cacheKey{apiKey: apiKey, interactive: true})
+}
+
+// expired reports whether a finished entry is too old to be reused. Must be called with the
+// cache's lock held.
+func (e *cacheEntry[T]) expired() bool {
+	return e.finished() && time.Since(e.fetchedAt) > cacheTTL
+}
+
+func (e *cacheEntry[T]) finished() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,238 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"mystrom/apiserver"
+	"mystrom/conf"
+	"mystrom/metrics"
+	nethttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxRetries is how often a request throttled or failed by myStrom is repeated.
+	maxRetries = 3
+
+	// The backoff after a throttled or failed request doubles from minBackoff up to maxBackoff.
+	minBackoff = 2 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Requests are limited per myStrom account and API version, so that configurations sharing an
+// API key share the budget, too.
+var (
+	apiV1Limits = newLimits("v1")
+	apiV2Limits = newLimits("v2")
+)
+
+type limits struct {
+	api string
+
+	mu       sync.Mutex
+	accounts map[string]*account
+}
+
+// account holds the request budget of one myStrom account as a token bucket, and the backoff
+// after myStrom throttled or failed requests.
+type account struct {
+	api    string
+	tokens *rate.Limiter
+
+	mu          sync.Mutex
+	failures    int
+	pausedUntil time.Time
+}
+
+// RateLimitedError is returned for interactive requests instead of waiting for the rate limit or
+// the backoff of the account.
+type RateLimitedError struct {
+	API     string
+	RetryAt time.Time
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit of myStrom API %s reached, retry at %s", e.API, e.RetryAt.Format(time.RFC3339))
+}
+
+type interactiveKey struct{}
+
+// Interactive marks the requests made with the context as made on behalf of a user waiting for
+// the answer. They fail with a RateLimitedError rather than waiting for the rate limit.
+func Interactive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, interactiveKey{}, true)
+}
+
+func isInteractive(ctx context.Context) bool {
+	interactive, _ := ctx.Value(interactiveKey{}).(bool)
+	return interactive
+}
+
+func newLimits(api string) *limits {
+	return &limits{api: api, accounts: make(map[string]*account)}
+}
+
+// apiV1Account returns the API v1 budget of the account. As API v1 is only used for discoveries,
+// a budget created after a restart is seeded with the last discovery, so that an app restarting
+// over and over does not query myStrom every time.
+func apiV1Account(ctx context.Context, config apiserver.Configuration) *account {
+	return apiV1Limits.account(config.ApiKey, perHour(config.ApiV1RateLimit, conf.DefaultApiV1RateLimit), func() time.Time {
+		last, err := conf.LastDiscoveryAt(ctx, config.ApiKey)
+		if err != nil {
+			log.Warn("broker", "seeding rate limit of myStrom API v1: %v", err)
+		}
+		return last
+	})
+}
+
+func apiV2Account(config apiserver.Configuration) *account {
+	return apiV2Limits.account(config.ApiKey, perHour(config.ApiV2RateLimit, conf.DefaultApiV2RateLimit), nil)
+}
+
+func perHour(limit *int32, def int32) int32 {
+	if common.Val(limit) <= 0 {
+		return def
+	}
+	return *limit
+}
+
+// account returns the budget of the account. If configurations of the account have different
+// limits, the limit of the last request applies. A new budget starts full, unless lastRequest
+// tells when the account sent its last request.
+func (l *limits) account(apiKey string, perHour int32, lastRequest func() time.Time) *account {
+	limit := rate.Limit(float64(perHour) / time.Hour.Seconds())
+	// Allows a few requests in a row, e.g. testing a configuration and discovering right after.
+	burst := max(2, int(perHour/12))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.accounts[apiKey]
+	if !ok {
+		a = &account{api: l.api, tokens: rate.NewLimiter(limit, burst)}
+		if lastRequest != nil {
+			a.seed(lastRequest())
+		}
+		l.accounts[apiKey] = a
+	} else if a.tokens.Limit() != limit {
+		a.tokens.SetLimit(limit)
+		a.tokens.SetBurst(burst)
+	}
+	return a
+}
+
+// seed takes the tokens out of the full bucket that have not been refilled since the last
+// request, assuming the bucket was empty after it.
+func (a *account) seed(last time.Time) {
+	if last.IsZero() {
+		return
+	}
+	refilled := int(time.Since(last).Seconds() * float64(a.tokens.Limit()))
+	if spent := a.tokens.Burst() - refilled; spent > 0 {
+		a.tokens.AllowN(time.Now(), spent)
+	}
+}
+
+// wait blocks until the account may send the next request. Requests over the limit are deferred,
+// not failed, unless they are interactive.
+func (a *account) wait(ctx context.Context) error {
+	a.mu.Lock()
+	pausedUntil := a.pausedUntil
+	a.mu.Unlock()
+	if pause := time.Until(pausedUntil); pause > 0 {
+		if isInteractive(ctx) {
+			return &RateLimitedError{API: a.api, RetryAt: pausedUntil}
+		}
+		if err := sleep(ctx, pause); err != nil {
+			return err
+		}
+	}
+
+	r := a.tokens.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if isInteractive(ctx) {
+		r.Cancel()
+		return &RateLimitedError{API: a.api, RetryAt: time.Now().Add(delay)}
+	}
+	log.Debug("broker", "Rate limit of myStrom API %s reached, deferring request by %v.", a.api, delay.Round(time.Second))
+	metrics.RequestDeferred(a.api)
+	if err := sleep(ctx, delay); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// backoff pauses all requests of the account for an exponentially growing, jittered time and
+// returns it.
+func (a *account) backoff() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failures++
+	d := min(minBackoff<<min(a.failures-1, 16), maxBackoff)
+	d = d/2 + rand.N(d/2)
+	a.pausedUntil = time.Now().Add(d)
+	return d
+}
+
+func (a *account) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failures = 0
+}
+
+// request sends a request to myStrom within the budget of the account. Requests throttled by
+// myStrom (429) or failed by it (5xx) are repeated after a backoff.
+func request[T any](ctx context.Context, a *account, do func(context.Context) (T, int, error)) (T, int, error) {
+	for attempt := 0; ; attempt++ {
+		if err := a.wait(ctx); err != nil {
+			var zero T
+			return zero, 0, err
+		}
+		resp, statusCode, err := do(ctx)
+		if statusCode != nethttp.StatusTooManyRequests && statusCode < 500 {
+			a.reset()
+			return resp, statusCode, err
+		}
+		backoff := a.backoff()
+		if attempt == maxRetries {
+			return resp, statusCode, err
+		}
+		log.Warn("broker", "myStrom API %s answered with status %d, retrying in %v.", a.api, statusCode, backoff.Round(time.Second))
+		metrics.RequestRetried(a.api, statusCode)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,206 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mystrom/apiserver"
+	"mystrom/broker"
+	"mystrom/conf"
+	"mystrom/eliona"
+	"mystrom/metrics"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// Summary describes the outcome of a single discovery or poll run.
+type Summary struct {
+	AssetsCreated  int
+	AssetsRenamed  int
+	DevicesUpdated int
+	Errors         []error
+}
+
+// Err returns all errors of the run joined together, or nil if the run succeeded.
+func (s Summary) Err() error {
+	return errors.Join(s.Errors...)
+}
+
+// Discoveries and polls of one configuration are serialized each, so that runs triggered through
+// the API do not interfere with the scheduled ones. A discovery and a poll may run at the same
+// time.
+var (
+	discoveryLocks sync.Map
+	pollLocks      sync.Map
+)
+
+// ErrRunInProgress is returned by runs triggered through the API while a run of the same kind is
+// in progress for the configuration.
+var ErrRunInProgress = errors.New("run in progress")
+
+func mutex(locks *sync.Map, configID int64) *sync.Mutex {
+	l, _ := locks.LoadOrStore(configID, &sync.Mutex{})
+	return l.(*sync.Mutex)
+}
+
+// Discover fetches all devices of the configuration, creates assets for new ones and writes
+// their current data. Meant for runs triggered through the API: fails with ErrRunInProgress
+// rather than waiting for a discovery of the configuration that is in progress.
+func Discover(ctx context.Context, config apiserver.Configuration) (Summary, error) {
+	mu := mutex(&discoveryLocks, *config.Id)
+	if !mu.TryLock() {
+		return Summary{}, ErrRunInProgress
+	}
+	defer mu.Unlock()
+	return discover(ctx, config), nil
+}
+
+func discover(ctx context.Context, config apiserver.Configuration) (summary Summary) {
+	// Picks up mappings changed outside of this app, e.g. through another replica.
+	conf.ForgetAssetIds(*config.Id)
+	var deviceCount int
+	defer func() {
+		recordStatus(ctx, config, summary, func() error {
+			return conf.RecordDiscovery(ctx, *config.Id, deviceCount)
+		})
+	}()
+
+	root, err := broker.GetDevices(ctx, config)
+	if err != nil {
+		log.Error("broker", "getting root: %v", err)
+		summary.Errors = append(summary.Errors, fmt.Errorf("getting devices: %w", err))
+		return summary
+	}
+	recordDiscovery(ctx, *config.Id, root)
+	deviceCount = len(root.Switches) + len(root.Excluded)
+	summary.AssetsCreated, err = eliona.CreateAssets(ctx, config, &root)
+	if err != nil {
+		log.Error("eliona", "creating assets: %v", err)
+		summary.Errors = append(summary.Errors, fmt.Errorf("creating assets: %v", err))
+		return summary
+	}
+	if common.Val(config.RenameExistingAssets) {
+		summary.AssetsRenamed, err = eliona.RenameAssets(ctx, config, root.GetDevices())
+		if err != nil {
+			log.Error("eliona", "renaming assets: %v", err)
+			summary.Errors = append(summary.Errors, fmt.Errorf("renaming assets: %v", err))
+		} else if err := conf.ResetRenameExistingAssets(ctx, *config.Id); err != nil {
+			log.Error("conf", "resetting rename flag: %v", err)
+			summary.Errors = append(summary.Errors, err)
+		}
+	}
+	summary.DevicesUpdated, err = eliona.UpsertSwitchData(ctx, config, root.GetDevices())
+	if err != nil {
+		log.Error("eliona", "inserting data into Eliona: %v", err)
+		summary.Errors = append(summary.Errors, fmt.Errorf("inserting data: %v", err))
+	}
+	return summary
+}
+
+// Poll fetches the current data of all devices of the configuration and writes it to Eliona. Like
+// Discover, fails with ErrRunInProgress if a poll of the configuration is in progress.
+func Poll(ctx context.Context, config apiserver.Configuration) (Summary, error) {
+	mu := mutex(&pollLocks, *config.Id)
+	if !mu.TryLock() {
+		return Summary{}, ErrRunInProgress
+	}
+	defer mu.Unlock()
+	return poll(ctx, config), nil
+}
+
+func poll(ctx context.Context, config apiserver.Configuration) (summary Summary) {
+	start := time.Now()
+	defer func() {
+		metrics.ObservePoll(*config.Id, time.Since(start))
+	}()
+	defer func() {
+		recordStatus(ctx, config, summary, func() error {
+			return conf.RecordPoll(ctx, *config.Id)
+		})
+	}()
+
+	devices, err := broker.GetData(ctx, config)
+	if err != nil {
+		log.Error("broker", "getting data: %v", err)
+		summary.Errors = append(summary.Errors, fmt.Errorf("getting data: %w", err))
+		return summary
+	}
+	recordData(ctx, *config.Id, devices)
+	summary.DevicesUpdated, err = eliona.UpsertSwitchData(ctx, config, devices)
+	if err != nil {
+		log.Error("eliona", "inserting data into Eliona: %v", err)
+		summary.Errors = append(summary.Errors, fmt.Errorf("inserting data: %v", err))
+	}
+	return summary
+}
+
+// recordStatus stores the outcome of a run in the runtime status of the configuration. Runs
+// cancelled by shutdown or configuration changes are not recorded.
+func recordStatus(ctx context.Context, config apiserver.Configuration, summary Summary, recordSuccess func() error) {
+	if ctx.Err() != nil {
+		return
+	}
+	runErr := summary.Err()
+	var rateLimited *broker.RateLimitedError
+	if errors.As(runErr, &rateLimited) {
+		// Turned down before asking myStrom, which says nothing about the configuration.
+		return
+	}
+	if runErr == nil {
+		if err := recordSuccess(); err != nil {
+			log.Error("conf", "recording status of configuration %d: %v", *config.Id, err)
+		}
+		return
+	}
+	authFailure := errors.Is(runErr, broker.ErrUnauthorized)
+	authFailures, err := conf.RecordFailure(ctx, *config.Id, runErr, authFailure)
+	if err != nil {
+		log.Error("conf", "recording status of configuration %d: %v", *config.Id, err)
+		return
+	}
+	if authFailure && authFailures >= maxAuthFailures {
+		suspend(ctx, config)
+	}
+}
+
+// maxAuthFailures is the number of consecutive runs rejected by myStrom after which a
+// configuration is suspended.
+const maxAuthFailures = 3
+
+// suspend stops collecting for the configuration until its API key is changed and tells the user.
+// The user is told only once, even if the discovery and the poll both suspend it.
+func suspend(ctx context.Context, config apiserver.Configuration) {
+	suspended, err := conf.SuspendConfig(ctx, *config.Id)
+	if err != nil {
+		log.Error("conf", "suspending configuration %d: %v", *config.Id, err)
+		return
+	}
+	if !suspended {
+		// Already suspended by the other job.
+		Stop(*config.Id)
+		return
+	}
+	log.Warn("collector", "Suspended configuration %d: API key rejected %d times in a row.", *config.Id, maxAuthFailures)
+	if err := eliona.NotifySuspended(ctx, config); err != nil {
+		log.Error("eliona", "notifying about suspended configuration %d: %v", *config.Id, err)
+	}
+	Stop(*config.Id)
+}
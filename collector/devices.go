@@ -0,0 +1,251 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mystrom/apiserver"
+	"mystrom/conf"
+	"mystrom/leader"
+	"mystrom/model"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-eliona/asset"
+	"github.com/eliona-smart-building-assistant/go-eliona/utils"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// DeviceState is what the app knows about a device seen at the last discovery.
+type DeviceState struct {
+	ID       string
+	Type     string
+	Name     string
+	RoomID   string
+	RoomName string
+	GAI      string
+
+	// Excluded is true if the asset filter rejected the device.
+	Excluded bool
+
+	// Properties holds the properties of the device the asset filter can match on.
+	Properties map[string]string
+
+	// Values holds the last values read from myStrom, keyed by attribute name.
+	Values    map[string]interface{}
+	UpdatedAt time.Time
+}
+
+type snapshot struct {
+	mu      sync.RWMutex
+	devices []DeviceState
+	byGAI   map[string]int
+}
+
+// snapshots holds the last discovery of each configuration. It is also stored in the database,
+// where standby replicas read it.
+var snapshots sync.Map
+
+func recordDiscovery(ctx context.Context, configID int64, root model.Root) {
+	now := time.Now()
+	s := &snapshot{byGAI: make(map[string]int)}
+	add := func(d model.Device, excluded bool) {
+		properties, err := utils.StructToMap(d)
+		if err != nil {
+			log.Error("collector", "getting filter properties of %v: %v", d.GetGAI(), err)
+		}
+		s.byGAI[d.GetGAI()] = len(s.devices)
+		s.devices = append(s.devices, DeviceState{
+			ID:         d.GetID(),
+			Type:       d.GetType(),
+			Name:       d.GetDeviceName(),
+			RoomID:     d.GetRoomID(),
+			RoomName:   d.GetRoomName(),
+			GAI:        d.GetGAI(),
+			Excluded:   excluded,
+			Properties: properties,
+			Values:     values(d),
+			UpdatedAt:  now,
+		})
+	}
+	for _, node := range root.Switches {
+		if d, ok := node.(model.Device); ok {
+			add(d, false)
+		}
+	}
+	for _, d := range root.Excluded {
+		add(d, true)
+	}
+	snapshots.Store(configID, s)
+	s.store(ctx, configID)
+}
+
+// recordData stores the polled values and completes the devices with what is only known from
+// discovery. Before the first discovery after app start, the devices stored in the database are
+// used.
+func recordData(ctx context.Context, configID int64, devices []asset.Asset) {
+	s, err := loadSnapshot(ctx, configID)
+	if err != nil {
+		log.Error("collector", "loading devices of config %d: %v", configID, err)
+		return
+	}
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.store(ctx, configID)
+	defer s.mu.Unlock()
+	for _, d := range devices {
+		i, ok := s.byGAI[d.GetGAI()]
+		if !ok {
+			// Device added after the last discovery.
+			continue
+		}
+		s.devices[i].Values = values(d)
+		s.devices[i].UpdatedAt = now
+		if device, ok := d.(model.Device); ok {
+			// Data from API v2 does not contain rooms.
+			device.SetRoom(s.devices[i].RoomID, s.devices[i].RoomName)
+		}
+	}
+}
+
+// loadSnapshot returns the snapshot of the configuration, reading it from the database if this
+// replica has none in memory. Returns nil if no discovery has finished yet.
+func loadSnapshot(ctx context.Context, configID int64) (*snapshot, error) {
+	if v, ok := snapshots.Load(configID); ok {
+		return v.(*snapshot), nil
+	}
+	data, err := conf.DiscoveredDevices(ctx, configID)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	s := &snapshot{byGAI: make(map[string]int)}
+	if err := json.Unmarshal(data, &s.devices); err != nil {
+		return nil, fmt.Errorf("unmarshalling devices of config %d: %v", configID, err)
+	}
+	for i, d := range s.devices {
+		s.byGAI[d.GAI] = i
+	}
+	// A discovery finishing meanwhile wins.
+	v, _ := snapshots.LoadOrStore(configID, s)
+	return v.(*snapshot), nil
+}
+
+// store writes the snapshot to the database. Failures are only logged, as the snapshot is kept in
+// memory for this replica anyway.
+func (s *snapshot) store(ctx context.Context, configID int64) {
+	s.mu.RLock()
+	data, err := json.Marshal(s.devices)
+	s.mu.RUnlock()
+	if err != nil {
+		log.Error("collector", "marshalling devices of config %d: %v", configID, err)
+		return
+	}
+	if err := conf.StoreDiscoveredDevices(ctx, configID, data); err != nil {
+		log.Error("collector", "storing devices of config %d: %v", configID, err)
+	}
+}
+
+// Devices returns the devices seen at the last discovery of the configuration, or none if no
+// discovery has finished yet. The leader answers from memory, other replicas from the database.
+func Devices(ctx context.Context, configID int64) ([]DeviceState, error) {
+	if v, ok := snapshots.Load(configID); ok && leader.IsLeader() {
+		s := v.(*snapshot)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		devices := make([]DeviceState, len(s.devices))
+		copy(devices, s.devices)
+		return devices, nil
+	}
+	data, err := conf.DiscoveredDevices(ctx, configID)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var devices []DeviceState
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return nil, fmt.Errorf("unmarshalling devices of config %d: %v", configID, err)
+	}
+	return devices, nil
+}
+
+// Device returns the device with the given myStrom ID seen at the last discovery of the
+// configuration.
+func Device(ctx context.Context, configID int64, deviceID string) (DeviceState, bool, error) {
+	devices, err := Devices(ctx, configID)
+	if err != nil {
+		return DeviceState{}, false, err
+	}
+	for _, d := range devices {
+		if d.ID == deviceID {
+			return d, true, nil
+		}
+	}
+	return DeviceState{}, false, nil
+}
+
+// InProject reports whether the device with the given GAI belongs to the project according to the
+// project filters of the configuration. Devices not seen at the last discovery only belong to
+// projects without a filter.
+func InProject(ctx context.Context, config apiserver.Configuration, projectID string, gai string) (bool, error) {
+	if _, ok := config.ProjectFilters[projectID]; !ok {
+		return true, nil
+	}
+	devices, err := Devices(ctx, *config.Id)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range devices {
+		if d.GAI == gai {
+			return model.PropertiesInProject(d.Properties, config, projectID)
+		}
+	}
+	return false, nil
+}
+
+// Rooms returns the names of the myStrom rooms seen at the last discovery of the configuration,
+// keyed by room ID.
+func Rooms(ctx context.Context, configID int64) (map[string]string, error) {
+	devices, err := Devices(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+	rooms := make(map[string]string)
+	for _, d := range devices {
+		if d.RoomID != "" {
+			rooms[d.RoomID] = d.RoomName
+		}
+	}
+	return rooms, nil
+}
+
+// Forget drops everything known about the configuration, e.g. after it was deleted.
+func Forget(configID int64) {
+	snapshots.Delete(configID)
+}
+
+func values(a asset.Asset) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, attributes := range asset.SplitBySubtype(a) {
+		for name, value := range attributes {
+			result[name] = value
+		}
+	}
+	return result
+}
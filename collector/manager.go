@@ -0,0 +1,242 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package collector
+
+import (
+	"context"
+	"mystrom/apiserver"
+	"mystrom/conf"
+	"mystrom/leader"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+type worker struct {
+	// config is the latest version of the configuration. Changes not affecting collection are
+	// picked up by the next run without restarting the worker.
+	config    apiserver.Configuration
+	configMu  sync.Mutex
+	cancel    context.CancelFunc
+	discovery *job
+	poll      *job
+}
+
+var (
+	workersMu sync.Mutex
+	workers   = make(map[int64]*worker)
+	running   sync.WaitGroup
+	stopped   bool
+
+	// appCtx is the context the workers are derived from.
+	appCtx = context.Background()
+)
+
+// Init sets the context of the app. Workers started afterwards stop when it is cancelled. Meant
+// to be called once before collecting starts.
+func Init(ctx context.Context) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	appCtx = ctx
+}
+
+// Apply makes the running worker reflect the configuration: an enabled configuration gets a new
+// worker using the current settings, a disabled or suspended one is stopped. Meant to be called
+// whenever a configuration is created or changed. On a standby replica, nothing is started; the
+// leader picks up the change at its next Sync.
+func Apply(config apiserver.Configuration) {
+	if !collecting(config) {
+		Stop(*config.Id)
+		return
+	}
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	if stopped {
+		return
+	}
+	if w, ok := workers[*config.Id]; ok {
+		log.Info("collector", "Restarting collecting %d with changed configuration.", *config.Id)
+		w.cancel()
+	}
+	workers[*config.Id] = start(config)
+}
+
+// Stop stops the worker of the configuration. A run in progress is cancelled in the background.
+func Stop(configID int64) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	if w, ok := workers[configID]; ok {
+		log.Info("collector", "Stopping collecting %d.", configID)
+		w.cancel()
+		delete(workers, configID)
+	}
+}
+
+// Sync starts workers for enabled configurations that have none, restarts workers whose
+// configuration changed, e.g. through another replica, and stops workers of configurations that
+// are disabled, suspended or no longer exist.
+func Sync(configs []apiserver.Configuration) {
+	enabled := make(map[int64]apiserver.Configuration)
+	for _, config := range configs {
+		if collecting(config) {
+			enabled[*config.Id] = config
+		}
+	}
+
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	if stopped {
+		return
+	}
+	for id, w := range workers {
+		if _, ok := enabled[id]; !ok {
+			log.Info("collector", "Stopping collecting %d.", id)
+			w.cancel()
+			delete(workers, id)
+		}
+	}
+	for id, config := range enabled {
+		w, ok := workers[id]
+		if ok && sameSettings(w.current(), config) {
+			w.update(config)
+			continue
+		}
+		if ok {
+			log.Info("collector", "Restarting collecting %d with changed configuration.", id)
+			w.cancel()
+		}
+		workers[id] = start(config)
+	}
+}
+
+// NextRuns returns when the next scheduled discovery and poll of the configuration are due. Both
+// are nil if no worker is collecting for the configuration.
+func NextRuns(configID int64) (discovery, poll *time.Time) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	w, ok := workers[configID]
+	if !ok {
+		return nil, nil
+	}
+	return w.discovery.nextRun(), w.poll.nextRun()
+}
+
+// RequestPoll makes the worker of the configuration poll as soon as possible, e.g. to pick up the
+// state of a device that was switched. Requests made before the poll starts are served by a
+// single poll. Does nothing if no worker is collecting for the configuration.
+func RequestPoll(configID int64) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	if w, ok := workers[configID]; ok {
+		select {
+		case w.poll.wake <- struct{}{}:
+		default:
+			// A poll is requested already.
+		}
+	}
+}
+
+// Running reports whether a worker is collecting for the configuration.
+func Running(configID int64) bool {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+	_, ok := workers[configID]
+	return ok
+}
+
+// StopAll stops all workers and waits until their runs in progress are cancelled. No workers are
+// started afterwards.
+func StopAll() {
+	workersMu.Lock()
+	stopped = true
+	for id, w := range workers {
+		w.cancel()
+		delete(workers, id)
+	}
+	workersMu.Unlock()
+	running.Wait()
+}
+
+// sameSettings reports whether two versions of a configuration collect the same way. Flags the
+// collector maintains itself, like the active and suspended flags and the one-time renaming of
+// existing assets, are ignored, as is the owning user.
+func sameSettings(a, b apiserver.Configuration) bool {
+	for _, c := range []*apiserver.Configuration{&a, &b} {
+		c.Active, c.Suspended, c.RenameExistingAssets, c.UserId = nil, nil, nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// collecting reports whether this replica should run a worker for the configuration. Standby
+// replicas run none.
+func collecting(config apiserver.Configuration) bool {
+	return leader.IsLeader() && conf.IsConfigEnabled(config) && !conf.IsConfigSuspended(config)
+}
+
+func (w *worker) current() apiserver.Configuration {
+	w.configMu.Lock()
+	defer w.configMu.Unlock()
+	return w.config
+}
+
+func (w *worker) update(config apiserver.Configuration) {
+	w.configMu.Lock()
+	defer w.configMu.Unlock()
+	w.config = config
+}
+
+func start(config apiserver.Configuration) *worker {
+	ctx, cancel := context.WithCancel(appCtx)
+	w := &worker{
+		config: config,
+		cancel: cancel,
+	}
+	w.discovery = &job{
+		name:          "discovery",
+		configID:      *config.Id,
+		lock:          mutex(&discoveryLocks, *config.Id),
+		interval:      time.Second * time.Duration(config.RefreshInterval),
+		retryInterval: time.Second * time.Duration(config.DataPollInterval),
+		run:           func(ctx context.Context) Summary { return discover(ctx, w.current()) },
+	}
+	w.poll = &job{
+		name:          "poll",
+		configID:      *config.Id,
+		lock:          mutex(&pollLocks, *config.Id),
+		interval:      time.Second * time.Duration(config.DataPollInterval),
+		retryInterval: time.Second * time.Duration(config.DataPollInterval),
+		run:           func(ctx context.Context) Summary { return poll(ctx, w.current()) },
+		// Configurations of the same account poll together and share the device list.
+		aligned: true,
+		phase:   keyPhase(config.ApiKey, time.Second*time.Duration(config.DataPollInterval)),
+		wake:    make(chan struct{}, 1),
+	}
+	log.Info("collector", "Collecting %d started.", *config.Id)
+	running.Add(2)
+	go func() {
+		defer running.Done()
+		// Configurations started together do not all discover at the same moment.
+		w.discovery.loop(ctx, randomDelay(maxStartDelay))
+	}()
+	go func() {
+		defer running.Done()
+		// Polls do not wait for the first discovery. Devices without assets are skipped.
+		w.poll.loop(ctx, time.Until(w.poll.following(time.Now())))
+	}()
+	return w
+}
@@ -0,0 +1,166 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package collector
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+const (
+	// maxStartDelay bounds the random delay of the first discovery of a started worker.
+	maxStartDelay = 5 * time.Second
+
+	// busyRetry is how long a run waits at most before trying again when another run of the
+	// same kind is in progress.
+	busyRetry = 5 * time.Second
+)
+
+// job runs a discovery or poll of a configuration at a fixed interval with some jitter. A run
+// that is due while another run of the same kind is in progress, e.g. one triggered through the
+// API or one of a worker that was just replaced, is tried again shortly after.
+type job struct {
+	name          string
+	configID      int64
+	lock          *sync.Mutex
+	interval      time.Duration
+	retryInterval time.Duration // used instead of interval after a failed run
+	run           func(context.Context) Summary
+
+	// aligned makes the runs fall on fixed slots: phase past every multiple of the interval,
+	// instead of spreading them with random jitter.
+	aligned bool
+	phase   time.Duration
+
+	// wake makes the job run right away, see RequestPoll. Nil for jobs that cannot be woken.
+	wake chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// loop runs the job for the first time after the delay and then repeatedly until the context is
+// cancelled.
+func (j *job) loop(ctx context.Context, delay time.Duration) {
+	due := time.Now().Add(delay)
+	for {
+		j.setNext(due)
+		woken, ok := sleepUntil(ctx, due, j.wake)
+		if !ok {
+			return
+		}
+		if woken {
+			due = time.Now()
+		}
+		if !j.lock.TryLock() {
+			log.Debug("collector", "Postponing %s of configuration %d: another one is in progress.", j.name, j.configID)
+			due = time.Now().Add(jitter(min(j.retryInterval, busyRetry)))
+			continue
+		}
+		summary := j.run(ctx)
+		j.lock.Unlock()
+
+		if summary.Err() != nil {
+			// Error is logged by the run. Retry after a while.
+			due = time.Now().Add(jitter(j.retryInterval))
+			continue
+		}
+		// Keep the cadence, but skip the runs that were missed while this one was in progress.
+		skipped := 0
+		for due = j.following(due); !due.After(time.Now()); due = j.following(due) {
+			skipped++
+		}
+		if skipped > 0 {
+			log.Debug("collector", "Skipped %d runs of %s of configuration %d: the run took longer than the interval.", skipped, j.name, j.configID)
+		}
+	}
+}
+
+// following returns when the job is due after a run that was due at the given time.
+func (j *job) following(due time.Time) time.Time {
+	if j.aligned {
+		return nextSlot(due, j.interval, j.phase)
+	}
+	return due.Add(jitter(j.interval))
+}
+
+// nextSlot returns the first time after t that lies phase past a multiple of the interval. All
+// replicas and workers agree on the slots, as they are counted from the zero time.
+func nextSlot(t time.Time, interval, phase time.Duration) time.Time {
+	interval = max(interval, time.Second)
+	slot := t.Truncate(interval).Add(phase % interval)
+	for !slot.After(t) {
+		slot = slot.Add(interval)
+	}
+	return slot
+}
+
+// keyPhase derives the phase of aligned runs from the API key, so that the configurations of one
+// myStrom account run at the same time while different accounts spread out over the interval.
+func keyPhase(apiKey string, interval time.Duration) time.Duration {
+	interval = max(interval, time.Second)
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(apiKey))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+func (j *job) setNext(t time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.next = t
+}
+
+// nextRun returns when the job is due next. While it is running, that is the time it started.
+func (j *job) nextRun() *time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	next := j.next
+	return &next
+}
+
+// jitter adds up to a tenth of the interval at random, so that the runs of configurations with
+// the same interval spread out over time. Intervals below a second are raised to a second.
+func jitter(interval time.Duration) time.Duration {
+	interval = max(interval, time.Second)
+	return interval + randomDelay(interval/10)
+}
+
+func randomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// sleepUntil waits until the time or until woken, and reports false if the context was cancelled
+// before.
+func sleepUntil(ctx context.Context, t time.Time, wake <-chan struct{}) (woken bool, ok bool) {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return false, true
+	case <-wake:
+		return true, true
+	case <-ctx.Done():
+		return false, false
+	}
+}
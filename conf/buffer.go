@@ -0,0 +1,142 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package conf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/types"
+)
+
+// BufferedReading is device data that could not be written to Eliona yet.
+type BufferedReading struct {
+	ID        int64
+	AssetID   int32
+	Subtype   string
+	Data      map[string]interface{}
+	Timestamp time.Time
+}
+
+// BufferReadings stores readings to be written to Eliona later. Afterwards, readings older than
+// maxAge and the oldest readings beyond maxReadings are dropped. Returns the number of dropped
+// readings.
+func BufferReadings(ctx context.Context, configID int64, readings []BufferedReading, maxReadings int32, maxAge time.Duration) (int64, error) {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range readings {
+		data, err := json.Marshal(r.Data)
+		if err != nil {
+			return 0, fmt.Errorf("marshalling data: %v", err)
+		}
+		if _, err := queries.Raw(
+			`insert into mystrom.buffered_data (configuration_id, asset_id, subtype, data, timestamp)
+			values ($1, $2, $3, $4, $5)`,
+			configID, r.AssetID, r.Subtype, data, r.Timestamp,
+		).ExecContext(ctx, tx); err != nil {
+			return 0, fmt.Errorf("inserting buffered reading: %v", err)
+		}
+	}
+	res, err := queries.Raw(
+		`delete from mystrom.buffered_data
+		where configuration_id = $1 and (timestamp < $2 or id not in (
+			select id from mystrom.buffered_data where configuration_id = $1 order by id desc limit $3
+		))`,
+		configID, time.Now().Add(-maxAge), maxReadings,
+	).ExecContext(ctx, tx)
+	if err != nil {
+		return 0, fmt.Errorf("dropping buffered readings over the limits: %v", err)
+	}
+	dropped, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("counting dropped readings: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing transaction: %v", err)
+	}
+	return dropped, nil
+}
+
+// BufferedReadings returns up to limit of the oldest buffered readings of the configuration, in
+// the order they were buffered. Readings older than maxAge are dropped first.
+func BufferedReadings(ctx context.Context, configID int64, maxAge time.Duration, limit int) ([]BufferedReading, error) {
+	if _, err := queries.Raw(
+		`delete from mystrom.buffered_data where configuration_id = $1 and timestamp < $2`,
+		configID, time.Now().Add(-maxAge),
+	).ExecContext(ctx, boil.GetContextDB()); err != nil {
+		return nil, fmt.Errorf("dropping expired buffered readings: %v", err)
+	}
+	rows, err := queries.Raw(
+		`select id, asset_id, subtype, data, timestamp from mystrom.buffered_data
+		where configuration_id = $1 order by id limit $2`,
+		configID, limit,
+	).QueryContext(ctx, boil.GetContextDB())
+	if err != nil {
+		return nil, fmt.Errorf("fetching buffered readings: %v", err)
+	}
+	defer rows.Close()
+	var readings []BufferedReading
+	for rows.Next() {
+		var r BufferedReading
+		var data []byte
+		if err := rows.Scan(&r.ID, &r.AssetID, &r.Subtype, &data, &r.Timestamp); err != nil {
+			return nil, fmt.Errorf("scanning buffered reading: %v", err)
+		}
+		if err := json.Unmarshal(data, &r.Data); err != nil {
+			return nil, fmt.Errorf("unmarshalling buffered reading %d: %v", r.ID, err)
+		}
+		readings = append(readings, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fetching buffered readings: %v", err)
+	}
+	return readings, nil
+}
+
+// DeleteBufferedReadings removes readings that were written to Eliona or rejected by it.
+func DeleteBufferedReadings(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := queries.Raw(
+		`delete from mystrom.buffered_data where id = any($1)`,
+		types.Int64Array(ids),
+	).ExecContext(ctx, boil.GetContextDB()); err != nil {
+		return fmt.Errorf("deleting buffered readings: %v", err)
+	}
+	return nil
+}
+
+// CountBufferedReadings returns the number of readings of the configuration waiting to be written
+// to Eliona.
+func CountBufferedReadings(ctx context.Context, configID int64) (int64, error) {
+	var count int64
+	if err := queries.Raw(
+		`select count(*) from mystrom.buffered_data where configuration_id = $1`,
+		configID,
+	).QueryRowContext(ctx, boil.GetContextDB()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting buffered readings: %v", err)
+	}
+	return count, nil
+}
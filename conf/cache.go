@@ -0,0 +1,108 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package conf
+
+import (
+	"context"
+	"fmt"
+	"mystrom/appdb"
+	"sync"
+	"time"
+
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// assetIdTTL bounds how long asset IDs are cached, so that mappings changed through another
+// replica of the app are picked up even without a discovery.
+const assetIdTTL = 5 * time.Minute
+
+// The Eliona asset IDs of each configuration by project and GAI, loaded with a single query, so
+// that writing data does not query the database for every device.
+var (
+	assetIdsMu sync.Mutex
+	assetIds   = make(map[int64]*cachedAssetIds)
+)
+
+type cachedAssetIds struct {
+	ids      map[assetKey]int32
+	loadedAt time.Time
+}
+
+type assetKey struct {
+	projectID     string
+	globalAssetID string
+}
+
+// cachedAssetId returns the asset ID of the GAI in the project, loading all mappings of the
+// configuration if they are not cached.
+func cachedAssetId(ctx context.Context, configID int64, projectID string, globalAssetID string) (*int32, error) {
+	assetIdsMu.Lock()
+	defer assetIdsMu.Unlock()
+	cached, ok := assetIds[configID]
+	if !ok || time.Since(cached.loadedAt) > assetIdTTL {
+		var err error
+		if cached, err = loadAssetIds(ctx, configID); err != nil {
+			return nil, err
+		}
+		assetIds[configID] = cached
+	}
+	id, ok := cached.ids[assetKey{projectID, globalAssetID}]
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+func loadAssetIds(ctx context.Context, configID int64) (*cachedAssetIds, error) {
+	dbAssets, err := appdb.Assets(
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
+		appdb.AssetWhere.AssetID.IsNotNull(),
+		qm.OrderBy(appdb.AssetColumns.Pinned+" desc"),
+	).AllG(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching assets: %v", err)
+	}
+	cached := &cachedAssetIds{ids: make(map[assetKey]int32, len(dbAssets)), loadedAt: time.Now()}
+	for _, dbAsset := range dbAssets {
+		key := assetKey{dbAsset.ProjectID, dbAsset.GlobalAssetID}
+		if _, ok := cached.ids[key]; !ok {
+			// Pinned mappings come first and take precedence.
+			cached.ids[key] = dbAsset.AssetID.Int32
+		}
+	}
+	return cached, nil
+}
+
+// rememberAssetId adds a newly created mapping to the cache. Existing, e.g. pinned, mappings are
+// kept.
+func rememberAssetId(configID int64, projectID string, globalAssetID string, assetID int32) {
+	assetIdsMu.Lock()
+	defer assetIdsMu.Unlock()
+	if cached, ok := assetIds[configID]; ok {
+		key := assetKey{projectID, globalAssetID}
+		if _, ok := cached.ids[key]; !ok {
+			cached.ids[key] = assetID
+		}
+	}
+}
+
+// ForgetAssetIds drops the cached asset IDs of the configuration. Called at every discovery and
+// whenever mappings are changed or removed.
+func ForgetAssetIds(configID int64) {
+	assetIdsMu.Lock()
+	defer assetIdsMu.Unlock()
+	delete(assetIds, configID)
+}
@@ -17,20 +17,43 @@ package conf
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"mystrom/apiserver"
 	"mystrom/appdb"
+	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/frontend"
 	"github.com/eliona-smart-building-assistant/go-utils/common"
 	"github.com/volatiletech/null/v8"
 	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 var ErrBadRequest = errors.New("bad request")
 
+// DefaultRequestTimeout in seconds, matches the default of the configuration table.
+const DefaultRequestTimeout int32 = 120
+
+// Default rate limits in requests per hour, match the defaults of the configuration table.
+const (
+	DefaultApiV1RateLimit int32 = 6
+	DefaultApiV2RateLimit int32 = 720
+)
+
+// DefaultHeartbeatInterval in seconds, matches the default of the configuration table.
+const DefaultHeartbeatInterval int32 = 900
+
+// Default limits of the buffer of readings not written to Eliona yet, match the defaults of the
+// configuration table.
+const (
+	DefaultBufferMaxReadings int32 = 100000
+	DefaultBufferMaxAge      int32 = 7 * 24 * 3600
+)
+
 func InsertConfig(ctx context.Context, config apiserver.Configuration) (apiserver.Configuration, error) {
 	dbConfig, err := dbConfigFromApiConfig(ctx, config)
 	if err != nil {
@@ -39,7 +62,7 @@ func InsertConfig(ctx context.Context, config apiserver.Configuration) (apiserve
 	if err := dbConfig.InsertG(ctx, boil.Infer()); err != nil {
 		return apiserver.Configuration{}, fmt.Errorf("inserting DB config: %v", err)
 	}
-	return config, nil
+	return storedConfig(ctx, dbConfig.ID)
 }
 
 func UpsertConfig(ctx context.Context, config apiserver.Configuration) (apiserver.Configuration, error) {
@@ -47,10 +70,39 @@ func UpsertConfig(ctx context.Context, config apiserver.Configuration) (apiserve
 	if err != nil {
 		return apiserver.Configuration{}, fmt.Errorf("creating DB config from API config: %v", err)
 	}
-	if err := dbConfig.UpsertG(ctx, true, []string{"id"}, boil.Blacklist("id"), boil.Infer()); err != nil {
+	// The runtime status is maintained by the app and must not be reset by configuration changes.
+	keep := []string{
+		appdb.ConfigurationColumns.ID,
+		appdb.ConfigurationColumns.LastDiscoveryAt,
+		appdb.ConfigurationColumns.LastPollAt,
+		appdb.ConfigurationColumns.LastError,
+		appdb.ConfigurationColumns.LastErrorAt,
+		appdb.ConfigurationColumns.ConsecutiveFailures,
+		appdb.ConfigurationColumns.DeviceCount,
+		appdb.ConfigurationColumns.AssetCount,
+	}
+	existing, err := appdb.FindConfigurationG(ctx, dbConfig.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return apiserver.Configuration{}, fmt.Errorf("fetching DB config: %v", err)
+	}
+	if existing != nil && existing.APIKey == dbConfig.APIKey {
+		// A suspended configuration is only resumed with a new API key.
+		keep = append(keep, appdb.ConfigurationColumns.Suspended, appdb.ConfigurationColumns.AuthFailures)
+	}
+	if err := dbConfig.UpsertG(ctx, true, []string{"id"}, boil.Blacklist(keep...), boil.Infer()); err != nil {
 		return apiserver.Configuration{}, fmt.Errorf("inserting DB config: %v", err)
 	}
-	return config, nil
+	return storedConfig(ctx, dbConfig.ID)
+}
+
+// storedConfig reads back a configuration just written, so that callers get the ID and the
+// defaults applied by the database rather than the request body.
+func storedConfig(ctx context.Context, configID int64) (apiserver.Configuration, error) {
+	config, err := GetConfig(ctx, configID)
+	if err != nil {
+		return apiserver.Configuration{}, fmt.Errorf("reading back DB config: %v", err)
+	}
+	return *config, nil
 }
 
 func GetConfig(ctx context.Context, configID int64) (*apiserver.Configuration, error) {
@@ -88,6 +140,7 @@ func DeleteConfig(ctx context.Context, configID int64) error {
 	if count == 0 {
 		return ErrBadRequest
 	}
+	ForgetAssetIds(configID)
 	return nil
 }
 
@@ -101,6 +154,28 @@ func dbConfigFromApiConfig(ctx context.Context, apiConfig apiserver.Configuratio
 	if apiConfig.RequestTimeout != nil {
 		dbConfig.RequestTimeout = *apiConfig.RequestTimeout
 	}
+	dbConfig.APIV1RateLimit = common.Val(apiConfig.ApiV1RateLimit)
+	if dbConfig.APIV1RateLimit <= 0 {
+		dbConfig.APIV1RateLimit = DefaultApiV1RateLimit
+	}
+	dbConfig.APIV2RateLimit = common.Val(apiConfig.ApiV2RateLimit)
+	if dbConfig.APIV2RateLimit <= 0 {
+		dbConfig.APIV2RateLimit = DefaultApiV2RateLimit
+	}
+	dbConfig.PowerDeadband = common.Val(apiConfig.PowerDeadband)
+	dbConfig.TemperatureDeadband = common.Val(apiConfig.TemperatureDeadband)
+	dbConfig.HeartbeatInterval = DefaultHeartbeatInterval
+	if apiConfig.HeartbeatInterval != nil {
+		dbConfig.HeartbeatInterval = *apiConfig.HeartbeatInterval
+	}
+	dbConfig.BufferMaxReadings = DefaultBufferMaxReadings
+	if apiConfig.BufferMaxReadings != nil {
+		dbConfig.BufferMaxReadings = *apiConfig.BufferMaxReadings
+	}
+	dbConfig.BufferMaxAge = DefaultBufferMaxAge
+	if apiConfig.BufferMaxAge != nil {
+		dbConfig.BufferMaxAge = *apiConfig.BufferMaxAge
+	}
 	af, err := json.Marshal(apiConfig.AssetFilter)
 	if err != nil {
 		return appdb.Configuration{}, fmt.Errorf("marshalling assetFilter: %v", err)
@@ -110,6 +185,30 @@ func dbConfigFromApiConfig(ctx context.Context, apiConfig apiserver.Configuratio
 	if apiConfig.ProjectIDs != nil {
 		dbConfig.ProjectIds = *apiConfig.ProjectIDs
 	}
+	if apiConfig.IncludeDeviceIds != nil {
+		dbConfig.IncludeDeviceIds = *apiConfig.IncludeDeviceIds
+	}
+	if apiConfig.ExcludeDeviceIds != nil {
+		dbConfig.ExcludeDeviceIds = *apiConfig.ExcludeDeviceIds
+	}
+	if apiConfig.ParentAssets != nil {
+		pa, err := json.Marshal(apiConfig.ParentAssets)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling parentAssets: %v", err)
+		}
+		dbConfig.ParentAssets = null.JSONFrom(pa)
+	}
+	if apiConfig.ProjectFilters != nil {
+		pf, err := json.Marshal(apiConfig.ProjectFilters)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling projectFilters: %v", err)
+		}
+		dbConfig.ProjectFilters = null.JSONFrom(pf)
+	}
+	dbConfig.AssetNameTemplate = null.StringFromPtr(apiConfig.AssetNameTemplate)
+	if apiConfig.RenameExistingAssets != nil {
+		dbConfig.RenameExistingAssets = *apiConfig.RenameExistingAssets
+	}
 
 	env := frontend.GetEnvironment(ctx)
 	if env != nil {
@@ -127,6 +226,13 @@ func apiConfigFromDbConfig(dbConfig *appdb.Configuration) (apiConfig apiserver.C
 	apiConfig.RefreshInterval = dbConfig.RefreshInterval
 	apiConfig.DataPollInterval = dbConfig.DataPollInterval
 	apiConfig.RequestTimeout = &dbConfig.RequestTimeout
+	apiConfig.ApiV1RateLimit = &dbConfig.APIV1RateLimit
+	apiConfig.ApiV2RateLimit = &dbConfig.APIV2RateLimit
+	apiConfig.PowerDeadband = &dbConfig.PowerDeadband
+	apiConfig.TemperatureDeadband = &dbConfig.TemperatureDeadband
+	apiConfig.HeartbeatInterval = &dbConfig.HeartbeatInterval
+	apiConfig.BufferMaxReadings = &dbConfig.BufferMaxReadings
+	apiConfig.BufferMaxAge = &dbConfig.BufferMaxAge
 	if dbConfig.AssetFilter.Valid {
 		var af [][]apiserver.FilterRule
 		if err := json.Unmarshal(dbConfig.AssetFilter.JSON, &af); err != nil {
@@ -137,6 +243,25 @@ func apiConfigFromDbConfig(dbConfig *appdb.Configuration) (apiConfig apiserver.C
 	apiConfig.Active = dbConfig.Active.Ptr()
 	apiConfig.ProjectIDs = common.Ptr[[]string](dbConfig.ProjectIds)
 	apiConfig.UserId = dbConfig.UserID.Ptr()
+	apiConfig.IncludeDeviceIds = common.Ptr[[]string](dbConfig.IncludeDeviceIds)
+	apiConfig.ExcludeDeviceIds = common.Ptr[[]string](dbConfig.ExcludeDeviceIds)
+	if dbConfig.ParentAssets.Valid {
+		var pa map[string]apiserver.ParentAssets
+		if err := json.Unmarshal(dbConfig.ParentAssets.JSON, &pa); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling parentAssets: %v", err)
+		}
+		apiConfig.ParentAssets = pa
+	}
+	if dbConfig.ProjectFilters.Valid {
+		var pf map[string][][]apiserver.FilterRule
+		if err := json.Unmarshal(dbConfig.ProjectFilters.JSON, &pf); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling projectFilters: %v", err)
+		}
+		apiConfig.ProjectFilters = pf
+	}
+	apiConfig.AssetNameTemplate = dbConfig.AssetNameTemplate.Ptr()
+	apiConfig.RenameExistingAssets = &dbConfig.RenameExistingAssets
+	apiConfig.Suspended = &dbConfig.Suspended
 	return apiConfig, nil
 }
 
@@ -164,6 +289,139 @@ func SetConfigActiveState(ctx context.Context, config apiserver.Configuration, s
 	})
 }
 
+// ResetRenameExistingAssets clears the request to rename existing assets once they were renamed.
+func ResetRenameExistingAssets(ctx context.Context, configID int64) error {
+	if _, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(configID),
+	).UpdateAllG(ctx, appdb.M{
+		appdb.ConfigurationColumns.RenameExistingAssets: false,
+	}); err != nil {
+		return fmt.Errorf("resetting rename flag: %v", err)
+	}
+	return nil
+}
+
+// GetConfigStatus returns the runtime status of the configuration.
+func GetConfigStatus(ctx context.Context, configID int64) (*apiserver.ConfigurationStatus, error) {
+	dbConfig, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(configID),
+	).OneG(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBadRequest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching config from database: %v", err)
+	}
+	buffered, err := CountBufferedReadings(ctx, configID)
+	if err != nil {
+		return nil, err
+	}
+	return &apiserver.ConfigurationStatus{
+		Active:              dbConfig.Active.Bool,
+		LastDiscoveryAt:     dbConfig.LastDiscoveryAt.Ptr(),
+		LastPollAt:          dbConfig.LastPollAt.Ptr(),
+		LastError:           dbConfig.LastError.Ptr(),
+		LastErrorAt:         dbConfig.LastErrorAt.Ptr(),
+		ConsecutiveFailures: dbConfig.ConsecutiveFailures,
+		DeviceCount:         dbConfig.DeviceCount,
+		AssetCount:          dbConfig.AssetCount,
+		Suspended:           dbConfig.Suspended,
+		BufferedReadings:    buffered,
+	}, nil
+}
+
+// LastDiscoveryAt returns the time of the last successful discovery of any configuration using the
+// API key, or the zero time if there was none.
+func LastDiscoveryAt(ctx context.Context, apiKey string) (time.Time, error) {
+	var last null.Time
+	if err := queries.Raw(
+		`select max(last_discovery_at) from mystrom.configuration where api_key = $1`,
+		apiKey,
+	).QueryRowContext(ctx, boil.GetContextDB()).Scan(&last); err != nil {
+		return time.Time{}, fmt.Errorf("fetching last discovery: %v", err)
+	}
+	return last.Time, nil
+}
+
+// RecordDiscovery stores the outcome of a successful discovery in the runtime status of the
+// configuration. The number of assets is counted from the stored asset mappings.
+func RecordDiscovery(ctx context.Context, configID int64, deviceCount int) error {
+	assetCount, err := appdb.Assets(
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
+		appdb.AssetWhere.AssetID.IsNotNull(),
+	).CountG(ctx)
+	if err != nil {
+		return fmt.Errorf("counting assets: %v", err)
+	}
+	if _, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(configID),
+	).UpdateAllG(ctx, appdb.M{
+		appdb.ConfigurationColumns.LastDiscoveryAt:     time.Now(),
+		appdb.ConfigurationColumns.ConsecutiveFailures: 0,
+		appdb.ConfigurationColumns.AuthFailures:        0,
+		appdb.ConfigurationColumns.DeviceCount:         deviceCount,
+		appdb.ConfigurationColumns.AssetCount:          assetCount,
+	}); err != nil {
+		return fmt.Errorf("recording discovery: %v", err)
+	}
+	return nil
+}
+
+// RecordPoll stores a successful data poll in the runtime status of the configuration.
+func RecordPoll(ctx context.Context, configID int64) error {
+	if _, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(configID),
+	).UpdateAllG(ctx, appdb.M{
+		appdb.ConfigurationColumns.LastPollAt:          time.Now(),
+		appdb.ConfigurationColumns.ConsecutiveFailures: 0,
+		appdb.ConfigurationColumns.AuthFailures:        0,
+	}); err != nil {
+		return fmt.Errorf("recording poll: %v", err)
+	}
+	return nil
+}
+
+// RecordFailure stores the error of a failed discovery or data poll in the runtime status of the
+// configuration and counts the consecutive failures. Authentication failures are counted
+// separately; the count is returned.
+func RecordFailure(ctx context.Context, configID int64, runErr error, authFailure bool) (authFailures int32, err error) {
+	if err := queries.Raw(
+		`update mystrom.configuration
+		set last_error = $1, last_error_at = $2, consecutive_failures = consecutive_failures + 1,
+			auth_failures = case when $3 then auth_failures + 1 else 0 end
+		where id = $4
+		returning auth_failures`,
+		runErr.Error(), time.Now(), authFailure, configID,
+	).QueryRowContext(ctx, boil.GetContextDB()).Scan(&authFailures); err != nil {
+		return 0, fmt.Errorf("recording failure: %v", err)
+	}
+	return authFailures, nil
+}
+
+// SuspendConfig stops collecting for the configuration until its API key is changed. Returns
+// false if the configuration was already suspended.
+func SuspendConfig(ctx context.Context, configID int64) (bool, error) {
+	count, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(configID),
+		appdb.ConfigurationWhere.Suspended.EQ(false),
+	).UpdateAllG(ctx, appdb.M{
+		appdb.ConfigurationColumns.Suspended: true,
+		appdb.ConfigurationColumns.Active:    false,
+	})
+	if err != nil {
+		return false, fmt.Errorf("suspending config: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	if _, err := queries.Raw("select 1").ExecContext(ctx, boil.GetContextDB()); err != nil {
+		return fmt.Errorf("querying database: %v", err)
+	}
+	return nil
+}
+
 func ProjIds(config apiserver.Configuration) []string {
 	if config.ProjectIDs == nil {
 		return []string{}
@@ -179,6 +437,10 @@ func IsConfigEnabled(config apiserver.Configuration) bool {
 	return config.Enable == nil || *config.Enable
 }
 
+func IsConfigSuspended(config apiserver.Configuration) bool {
+	return config.Suspended != nil && *config.Suspended
+}
+
 func SetAllConfigsInactive(ctx context.Context) (int64, error) {
 	return appdb.Configurations().UpdateAllG(ctx, appdb.M{
 		appdb.ConfigurationColumns.Active: false,
@@ -192,33 +454,115 @@ func InsertAsset(ctx context.Context, config apiserver.Configuration, projId str
 	dbAsset.GlobalAssetID = globalAssetID
 	dbAsset.AssetID = null.Int32From(assetId)
 	dbAsset.ProviderID = providerId
-	return dbAsset.InsertG(ctx, boil.Infer())
+	if err := dbAsset.InsertG(ctx, boil.Infer()); err != nil {
+		return err
+	}
+	rememberAssetId(dbAsset.ConfigurationID, projId, globalAssetID, assetId)
+	return nil
 }
 
+// GetAssetId returns the Eliona asset ID of the GAI in the project, or nil if there is none. The
+// IDs are cached per configuration.
 func GetAssetId(ctx context.Context, config apiserver.Configuration, projId string, globalAssetID string) (*int32, error) {
-	dbAsset, err := appdb.Assets(
-		appdb.AssetWhere.ConfigurationID.EQ(*config.Id),
-		appdb.AssetWhere.ProjectID.EQ(projId),
-		appdb.AssetWhere.GlobalAssetID.EQ(globalAssetID),
+	return cachedAssetId(ctx, *config.Id, projId, globalAssetID)
+}
+
+// GetAssets returns all asset mappings of the configuration.
+func GetAssets(ctx context.Context, configID int64) (appdb.AssetSlice, error) {
+	assets, err := appdb.Assets(
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
 	).AllG(ctx)
-	if err != nil || len(dbAsset) == 0 {
-		return nil, err
+	if err != nil {
+		return nil, fmt.Errorf("fetching assets: %v", err)
 	}
-	return common.Ptr(dbAsset[0].AssetID.Int32), nil
+	return assets, nil
 }
 
-func GetAssetById(assetId int32) (appdb.Asset, error) {
+// GetAsset returns the asset mapping with the given ID of the configuration.
+func GetAsset(ctx context.Context, configID int64, mappingID int64) (*appdb.Asset, error) {
+	dbAsset, err := appdb.Assets(
+		appdb.AssetWhere.ID.EQ(mappingID),
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
+	).OneG(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBadRequest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching asset: %v", err)
+	}
+	return dbAsset, nil
+}
+
+// PinAsset binds the asset to the Eliona asset ID given by the user. Pinned mappings take
+// precedence over mappings created by continuous asset creation and are never changed by it. Other
+// mappings of the same asset in the project are removed.
+func PinAsset(ctx context.Context, dbAsset appdb.Asset) (*appdb.Asset, error) {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	others := []qm.QueryMod{
+		appdb.AssetWhere.ConfigurationID.EQ(dbAsset.ConfigurationID),
+		appdb.AssetWhere.ProjectID.EQ(dbAsset.ProjectID),
+		appdb.AssetWhere.GlobalAssetID.EQ(dbAsset.GlobalAssetID),
+	}
+	if dbAsset.ID != 0 {
+		others = append(others, appdb.AssetWhere.ID.NEQ(dbAsset.ID))
+	}
+	if _, err := appdb.Assets(others...).DeleteAll(ctx, tx); err != nil {
+		return nil, fmt.Errorf("deleting other mappings of asset: %v", err)
+	}
+
+	dbAsset.Pinned = true
+	if dbAsset.ID == 0 {
+		err = dbAsset.Insert(ctx, tx, boil.Infer())
+	} else {
+		var count int64
+		count, err = dbAsset.Update(ctx, tx, boil.Infer())
+		if err == nil && count == 0 {
+			return nil, ErrBadRequest
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storing asset: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %v", err)
+	}
+	ForgetAssetIds(dbAsset.ConfigurationID)
+	return &dbAsset, nil
+}
+
+// DeleteAsset removes the asset mapping with the given ID of the configuration.
+func DeleteAsset(ctx context.Context, configID int64, mappingID int64) error {
+	count, err := appdb.Assets(
+		appdb.AssetWhere.ID.EQ(mappingID),
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
+	).DeleteAllG(ctx)
+	if err != nil {
+		return fmt.Errorf("deleting asset from database: %v", err)
+	}
+	if count == 0 {
+		return ErrBadRequest
+	}
+	ForgetAssetIds(configID)
+	return nil
+}
+
+func GetAssetById(ctx context.Context, assetId int32) (appdb.Asset, error) {
 	asset, err := appdb.Assets(
 		appdb.AssetWhere.AssetID.EQ(null.Int32From(assetId)),
-	).OneG(context.Background())
+	).OneG(ctx)
 	if err != nil {
 		return appdb.Asset{}, fmt.Errorf("fetching asset: %v", err)
 	}
 	return *asset, nil
 }
 
-func GetConfigForAsset(asset appdb.Asset) (apiserver.Configuration, error) {
-	c, err := asset.Configuration().OneG(context.Background())
+func GetConfigForAsset(ctx context.Context, asset appdb.Asset) (apiserver.Configuration, error) {
+	c, err := asset.Configuration().OneG(ctx)
 	if err != nil {
 		return apiserver.Configuration{}, fmt.Errorf("fetching configuration: %v", err)
 	}
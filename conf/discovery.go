@@ -0,0 +1,56 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package conf
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// StoreDiscoveredDevices stores what the collector knows about the devices of the configuration,
+// encoded as JSON, so that every replica can serve it.
+func StoreDiscoveredDevices(ctx context.Context, configID int64, devices []byte) error {
+	if _, err := queries.Raw(
+		`insert into mystrom.discovered_devices (configuration_id, devices, updated_at) values ($1, $2, now())
+		on conflict (configuration_id) do update set devices = excluded.devices, updated_at = excluded.updated_at`,
+		configID, string(devices),
+	).ExecContext(ctx, boil.GetContextDB()); err != nil {
+		return fmt.Errorf("storing discovered devices: %v", err)
+	}
+	return nil
+}
+
+// DiscoveredDevices returns the devices stored by StoreDiscoveredDevices, or nil if none were
+// stored for the configuration.
+func DiscoveredDevices(ctx context.Context, configID int64) ([]byte, error) {
+	var devices []byte
+	err := queries.Raw(
+		`select devices from mystrom.discovered_devices where configuration_id = $1`,
+		configID,
+	).QueryRowContext(ctx, boil.GetContextDB()).Scan(&devices)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching discovered devices: %v", err)
+	}
+	return devices, nil
+}
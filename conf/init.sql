@@ -18,16 +18,38 @@ create schema if not exists mystrom;
 -- Should be editable by eliona frontend.
 create table if not exists mystrom.configuration
 (
-	id                 bigserial primary key,
-	api_key            text not null,
-	refresh_interval   integer not null default 3600,
-	data_poll_interval integer not null default 60,
-	request_timeout    integer not null default 120,
-	asset_filter       json,
-	active             boolean default false,
-	enable             boolean default false,
-	project_ids        text[],
-	user_id            text
+	id                     bigserial primary key,
+	api_key                text not null,
+	refresh_interval       integer not null default 3600,
+	data_poll_interval     integer not null default 60,
+	request_timeout        integer not null default 120,
+	asset_filter           json,
+	active                 boolean default false,
+	enable                 boolean default false,
+	project_ids            text[],
+	user_id                text,
+	include_device_ids     text[],
+	exclude_device_ids     text[],
+	parent_assets          json,
+	asset_name_template    text,
+	rename_existing_assets boolean not null default false,
+	project_filters        json,
+	last_discovery_at      timestamptz,
+	last_poll_at           timestamptz,
+	last_error             text,
+	last_error_at          timestamptz,
+	consecutive_failures   integer not null default 0,
+	device_count           integer not null default 0,
+	asset_count            integer not null default 0,
+	suspended              boolean not null default false,
+	auth_failures          integer not null default 0,
+	api_v1_rate_limit      integer not null default 6,
+	api_v2_rate_limit      integer not null default 720,
+	power_deadband         real not null default 0,
+	temperature_deadband   real not null default 0,
+	heartbeat_interval     integer not null default 900,
+	buffer_max_readings    integer not null default 100000,
+	buffer_max_age         integer not null default 604800
 );
 
 create table if not exists mystrom.asset
@@ -37,7 +59,28 @@ create table if not exists mystrom.asset
 	project_id       text      not null,
 	global_asset_id  text      not null,
 	provider_id      text      not null,
-	asset_id         integer
+	asset_id         integer,
+	pinned           boolean   not null default false
+);
+
+-- Device data that could not be written to Eliona yet.
+create table if not exists mystrom.buffered_data
+(
+	id               bigserial   primary key,
+	configuration_id bigint      not null references mystrom.configuration(id) ON DELETE CASCADE,
+	asset_id         integer     not null,
+	subtype          text        not null,
+	data             json        not null,
+	timestamp        timestamptz not null
+);
+
+create index if not exists buffered_data_configuration_id_idx on mystrom.buffered_data (configuration_id, id);
+
+create table if not exists mystrom.discovered_devices
+(
+	configuration_id bigint      primary key references mystrom.configuration(id) ON DELETE CASCADE,
+	devices          json        not null,
+	updated_at       timestamptz not null default now()
 );
 
 -- Makes the new objects available for all other init steps
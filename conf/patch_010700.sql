@@ -0,0 +1,22 @@
+--  This file is part of the eliona project.
+--  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+--  ______ _ _
+-- |  ____| (_)
+-- | |__  | |_  ___  _ __   __ _
+-- |  __| | | |/ _ \| '_ \ / _` |
+-- | |____| | | (_) | | | | (_| |
+-- |______|_|_|\___/|_| |_|\__,_|
+--
+--  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+--  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+--  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+--  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+--  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+alter table mystrom.configuration add column if not exists last_discovery_at    timestamptz;
+alter table mystrom.configuration add column if not exists last_poll_at         timestamptz;
+alter table mystrom.configuration add column if not exists last_error           text;
+alter table mystrom.configuration add column if not exists last_error_at        timestamptz;
+alter table mystrom.configuration add column if not exists consecutive_failures integer not null default 0;
+alter table mystrom.configuration add column if not exists device_count         integer not null default 0;
+alter table mystrom.configuration add column if not exists asset_count          integer not null default 0;
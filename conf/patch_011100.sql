@@ -0,0 +1,29 @@
+--  This file is part of the eliona project.
+--  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+--  ______ _ _
+-- |  ____| (_)
+-- | |__  | |_  ___  _ __   __ _
+-- |  __| | | |/ _ \| '_ \ / _` |
+-- | |____| | | (_) | | | | (_| |
+-- |______|_|_|\___/|_| |_|\__,_|
+--
+--  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+--  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+--  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+--  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+--  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+alter table mystrom.configuration add column if not exists buffer_max_readings integer not null default 100000;
+alter table mystrom.configuration add column if not exists buffer_max_age      integer not null default 604800;
+
+create table if not exists mystrom.buffered_data
+(
+	id               bigserial   primary key,
+	configuration_id bigint      not null references mystrom.configuration(id) ON DELETE CASCADE,
+	asset_id         integer     not null,
+	subtype          text        not null,
+	data             json        not null,
+	timestamp        timestamptz not null
+);
+
+create index if not exists buffered_data_configuration_id_idx on mystrom.buffered_data (configuration_id, id);
@@ -16,46 +16,303 @@
 package eliona
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"mystrom/apiserver"
+	"mystrom/appdb"
+	"mystrom/conf"
+	"mystrom/model"
+	"net/http"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
 	"github.com/eliona-smart-building-assistant/go-eliona/client"
+	"github.com/eliona-smart-building-assistant/go-utils/common"
 	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
-func CreateAssets(config apiserver.Configuration, root asset.Root) error {
-	for _, projectId := range *config.ProjectIDs {
-		assetsCreated, err := asset.CreateAssets(root, projectId)
+// CreateAssets creates missing assets in all projects of the configuration and returns the number
+// of assets created. Each project gets the devices passing its project filter. The root asset is
+// placed under the parent assets configured for the project.
+func CreateAssets(ctx context.Context, config apiserver.Configuration, root *model.Root) (int, error) {
+	total := 0
+	for _, projectId := range conf.ProjIds(config) {
+		if err := ctx.Err(); err != nil {
+			// The bulk creation of go-eliona cannot be cancelled, so stop before the next project.
+			return total, err
+		}
+		projectRoot, err := root.ForProject(projectId)
+		if err != nil {
+			return total, fmt.Errorf("selecting devices of project %v: %v", projectId, err)
+		}
+		c := newStructureCollector(ctx, projectId)
+		var rootParents parentAssets
+		if p, ok := config.ParentAssets[projectId]; ok {
+			rootParents.locational = parent{assetID: p.LocationalAssetId}
+			rootParents.functional = parent{assetID: p.FunctionalAssetId}
+		}
+		if err := c.collect(projectRoot, rootParents); err != nil {
+			return total, fmt.Errorf("collecting assets to create: %v", err)
+		}
+		assetsToCreate, err := c.resolve()
 		if err != nil {
-			return err
+			return total, fmt.Errorf("resolving parent assets: %v", err)
+		}
+		if len(assetsToCreate) == 0 {
+			continue
 		}
+		assetsCreated, err := asset.CreateAssetsBulk(assetsToCreate, projectId)
+		if err != nil {
+			return total, err
+		}
+		total += assetsCreated
 		if assetsCreated != 0 {
-			if err := notifyUser(*config.UserId, projectId, assetsCreated); err != nil {
-				return fmt.Errorf("notifying user about CAC: %v", err)
+			if err := notifyUser(ctx, *config.UserId, projectId, assetsCreated); err != nil {
+				return total, fmt.Errorf("notifying user about CAC: %v", err)
+			}
+		}
+	}
+	return total, nil
+}
+
+// RenameAssets updates the names of existing device assets created by the app, e.g. after the naming
+// template of the configuration changed. Assets pinned by users are left untouched. Returns the
+// number of renamed assets.
+func RenameAssets(ctx context.Context, config apiserver.Configuration, devices []asset.Asset) (int, error) {
+	mappings, err := conf.GetAssets(ctx, *config.Id)
+	if err != nil {
+		return 0, err
+	}
+	byGAI := make(map[string][]*appdb.Asset)
+	for _, m := range mappings {
+		byGAI[m.GlobalAssetID] = append(byGAI[m.GlobalAssetID], m)
+	}
+
+	renamed := 0
+	var errs []error
+	for _, d := range devices {
+		for _, m := range byGAI[d.GetGAI()] {
+			if m.Pinned || !m.AssetID.Valid {
+				continue
+			}
+			a, _, err := client.NewClient().AssetsAPI.
+				GetAssetById(client.AuthenticationContextWrap(ctx), m.AssetID.Int32).
+				Execute()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("fetching asset %v: %v", m.AssetID.Int32, err))
+				continue
+			}
+			if name := a.Name.Get(); name != nil && *name == d.GetName() {
+				continue
+			}
+			a.Name.Set(common.Ptr(d.GetName()))
+			if _, _, err := client.NewClient().AssetsAPI.
+				PutAsset(client.AuthenticationContextWrap(ctx)).
+				Asset(*a).
+				Execute(); err != nil {
+				errs = append(errs, fmt.Errorf("renaming asset %v: %v", m.AssetID.Int32, err))
+				continue
+			}
+			renamed++
+		}
+	}
+	return renamed, errors.Join(errs...)
+}
+
+// parent references the parent of an asset to create. Either the asset already exists in Eliona
+// and has an asset ID, or it is created in the same run and is referenced by its GAI.
+type parent struct {
+	gai     string
+	assetID *int32
+}
+
+type parentAssets struct {
+	locational parent
+	functional parent
+}
+
+type assetToCreate struct {
+	node    asset.Asset
+	parents parentAssets
+
+	locationalParentGAI string
+	functionalParentGAI string
+}
+
+func (a *assetToCreate) GetName() string {
+	return a.node.GetName()
+}
+
+func (a *assetToCreate) GetDescription() string {
+	return a.node.GetDescription()
+}
+
+func (a *assetToCreate) GetAssetType() string {
+	return a.node.GetAssetType()
+}
+
+func (a *assetToCreate) GetGAI() string {
+	return a.node.GetGAI()
+}
+
+func (a *assetToCreate) GetLocationalParentGAI() string {
+	return a.locationalParentGAI
+}
+
+func (a *assetToCreate) GetFunctionalParentGAI() string {
+	return a.functionalParentGAI
+}
+
+func (a *assetToCreate) SetAssetID(assetID int32, projectID string) error {
+	return a.node.SetAssetID(assetID, projectID)
+}
+
+// structureCollector walks the asset structure like asset.CreateAssets does. Unlike the library,
+// it references parents that already exist by the GAI they have in Eliona, which differs from the
+// GAI of the node for assets mapped by users or created by older versions of the app.
+type structureCollector struct {
+	ctx       context.Context
+	projectId string
+	visited   map[string]bool
+	toCreate  map[string]*assetToCreate
+	assets    []*assetToCreate
+	gais      map[int32]string
+}
+
+func newStructureCollector(ctx context.Context, projectId string) *structureCollector {
+	return &structureCollector{
+		ctx:       ctx,
+		projectId: projectId,
+		visited:   make(map[string]bool),
+		toCreate:  make(map[string]*assetToCreate),
+		gais:      make(map[int32]string),
+	}
+}
+
+func (c *structureCollector) collect(node asset.Asset, parents parentAssets) error {
+	if c.visited[node.GetGAI()] {
+		// The node is reachable both in the locational and the functional structure.
+		if a, ok := c.toCreate[node.GetGAI()]; ok {
+			if a.parents.locational == (parent{}) {
+				a.parents.locational = parents.locational
+			}
+			if a.parents.functional == (parent{}) {
+				a.parents.functional = parents.functional
+			}
+		}
+		return nil
+	}
+	c.visited[node.GetGAI()] = true
+
+	assetID, err := node.GetAssetID(c.projectId)
+	if err != nil {
+		return fmt.Errorf("getting asset ID: %v", err)
+	}
+	if assetID == nil {
+		a := &assetToCreate{node: node, parents: parents}
+		c.toCreate[node.GetGAI()] = a
+		c.assets = append(c.assets, a)
+	}
+	self := parent{gai: node.GetGAI(), assetID: assetID}
+
+	if ln, ok := node.(asset.LocationalNode); ok {
+		for _, child := range ln.GetLocationalChildren() {
+			if child == nil {
+				continue
+			}
+			if err := c.collect(child, parentAssets{locational: self, functional: parents.functional}); err != nil {
+				return err
+			}
+		}
+	}
+	if fn, ok := node.(asset.FunctionalNode); ok {
+		for _, child := range fn.GetFunctionalChildren() {
+			if child == nil {
+				continue
+			}
+			if err := c.collect(child, parentAssets{locational: parents.locational, functional: self}); err != nil {
+				return err
 			}
 		}
 	}
 	return nil
 }
 
-func notifyUser(userId string, projectId string, assetsCreated int) error {
+// resolve sets the parent GAIs of all assets to create. Existing parents are looked up in Eliona.
+func (c *structureCollector) resolve() ([]asset.AssetWithParentReferences, error) {
+	result := make([]asset.AssetWithParentReferences, 0, len(c.assets))
+	for _, a := range c.assets {
+		var err error
+		if a.locationalParentGAI, err = c.parentGAI(a.parents.locational); err != nil {
+			return nil, err
+		}
+		if a.functionalParentGAI, err = c.parentGAI(a.parents.functional); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+func (c *structureCollector) parentGAI(p parent) (string, error) {
+	if p.assetID == nil {
+		return p.gai, nil
+	}
+	if gai, ok := c.gais[*p.assetID]; ok {
+		return gai, nil
+	}
+	a, res, err := client.NewClient().AssetsAPI.
+		GetAssetById(client.AuthenticationContextWrap(c.ctx), *p.assetID).
+		Execute()
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("parent asset %v does not exist in Eliona", *p.assetID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("fetching parent asset %v: %v", *p.assetID, err)
+	}
+	c.gais[*p.assetID] = a.GlobalAssetIdentifier
+	return a.GlobalAssetIdentifier, nil
+}
+
+func notifyUser(ctx context.Context, userId string, projectId string, assetsCreated int) error {
+	return notify(ctx, userId, projectId, api.Translation{
+		De: api.PtrString(fmt.Sprintf("myStrom App hat %d neue Assets angelegt. Diese sind nun im Asset-Management verfügbar.", assetsCreated)),
+		En: api.PtrString(fmt.Sprintf("myStrom app added %v new assets. They are now available in Asset Management.", assetsCreated)),
+	})
+}
+
+// NotifySuspended tells the user who configured the app that collecting was suspended because
+// myStrom rejected the API key.
+func NotifySuspended(ctx context.Context, config apiserver.Configuration) error {
+	if config.UserId == nil {
+		return fmt.Errorf("no user to notify")
+	}
+	var errs []error
+	for _, projectId := range conf.ProjIds(config) {
+		if err := notify(ctx, *config.UserId, projectId, api.Translation{
+			De: api.PtrString(fmt.Sprintf("myStrom App: Der API-Schlüssel der Konfiguration %d wurde abgelehnt. Die Datenerfassung ist angehalten, bis ein neuer Schlüssel hinterlegt wird.", *config.Id)),
+			En: api.PtrString(fmt.Sprintf("myStrom app: The API key of configuration %d was rejected. Collecting is suspended until a new key is set.", *config.Id)),
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func notify(ctx context.Context, userId string, projectId string, message api.Translation) error {
 	receipt, _, err := client.NewClient().CommunicationAPI.
-		PostNotification(client.AuthenticationContext()).
+		PostNotification(client.AuthenticationContextWrap(ctx)).
 		Notification(
 			api.Notification{
 				User:      userId,
 				ProjectId: *api.NewNullableString(&projectId),
-				Message: *api.NewNullableTranslation(&api.Translation{
-					De: api.PtrString(fmt.Sprintf("myStrom App hat %d neue Assets angelegt. Diese sind nun im Asset-Management verfügbar.", assetsCreated)),
-					En: api.PtrString(fmt.Sprintf("myStrom app added %v new assets. They are now available in Asset Management.", assetsCreated)),
-				}),
+				Message:   *api.NewNullableTranslation(&message),
 			}).
 		Execute()
-	log.Debug("eliona", "posted notification about CAC: %v", receipt)
+	log.Debug("eliona", "posted notification: %v", receipt)
 	if err != nil {
-		return fmt.Errorf("posting CAC notification: %v", err)
+		return fmt.Errorf("posting notification: %v", err)
 	}
 	return nil
 }
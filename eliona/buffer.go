@@ -0,0 +1,229 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package eliona
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mystrom/apiserver"
+	"mystrom/conf"
+	"mystrom/metrics"
+	"sync"
+	"time"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// replayBatchSize is the number of buffered readings read from the database at once.
+const replayBatchSize = 500
+
+// errBufferUnavailable marks replay failures caused by the app's database rather than Eliona.
+var errBufferUnavailable = errors.New("buffer unavailable")
+
+var (
+	configLocksMu sync.Mutex
+	configLocks   = make(map[int64]*sync.Mutex)
+
+	// mayHaveBuffered tells for each configuration whether the buffer has to be checked. Unknown
+	// configurations are checked, as readings may have been buffered before a restart.
+	mayHaveBufferedMu sync.Mutex
+	mayHaveBuffered   = make(map[int64]bool)
+)
+
+func configLock(configID int64) *sync.Mutex {
+	configLocksMu.Lock()
+	defer configLocksMu.Unlock()
+	if configLocks[configID] == nil {
+		configLocks[configID] = &sync.Mutex{}
+	}
+	return configLocks[configID]
+}
+
+func hasBuffered(configID int64) bool {
+	mayHaveBufferedMu.Lock()
+	defer mayHaveBufferedMu.Unlock()
+	buffered, known := mayHaveBuffered[configID]
+	return buffered || !known
+}
+
+func setBuffered(configID int64, buffered bool) {
+	mayHaveBufferedMu.Lock()
+	defer mayHaveBufferedMu.Unlock()
+	mayHaveBuffered[configID] = buffered
+}
+
+func buffering(config apiserver.Configuration) bool {
+	return config.BufferMaxReadings == nil || *config.BufferMaxReadings > 0
+}
+
+// write writes the readings to Eliona and returns the number of updated assets. Buffered readings
+// are replayed first. Readings that could not be written because Eliona is unreachable are
+// buffered, as are all new readings while older ones are still waiting, to keep their order. If
+// the buffer cannot be read, the new readings are written anyway.
+func write(ctx context.Context, config apiserver.Configuration, readings []reading) (int, error) {
+	lock := configLock(*config.Id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if buffering(config) {
+		drained, err := replay(ctx, config)
+		if errors.Is(err, errBufferUnavailable) {
+			// Buffering the new readings would most likely fail as well, while Eliona may be
+			// reachable.
+			log.Error("Eliona", "replaying buffered readings of config %d: %v", *config.Id, err)
+		} else if err != nil || !drained {
+			// The older readings are written first.
+			if bufErr := buffer(ctx, config, readings); bufErr != nil {
+				return 0, errors.Join(err, bufErr)
+			}
+			return 0, err
+		}
+	}
+
+	result := writeAll(ctx, byAsset(readings), maxConcurrentWrites)
+	for _, r := range result.written {
+		remember(r.assetId, r.subtype, r.data, r.timestamp)
+	}
+	for range result.errs {
+		metrics.UpsertError(*config.Id)
+	}
+	errs := result.errs
+	if len(result.unreachable) > 0 && buffering(config) {
+		if err := buffer(ctx, config, result.unreachable); err != nil {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, fmt.Errorf("buffered %d readings until Eliona is reachable again", len(result.unreachable)))
+		}
+	}
+	return result.assets(), errors.Join(errs...)
+}
+
+// buffer stores the readings to be replayed later. They are remembered as written, so that
+// unchanged data is not buffered again.
+func buffer(ctx context.Context, config apiserver.Configuration, readings []reading) error {
+	if len(readings) == 0 {
+		return nil
+	}
+	buffered := make([]conf.BufferedReading, len(readings))
+	for i, r := range readings {
+		buffered[i] = conf.BufferedReading{
+			AssetID:   r.assetId,
+			Subtype:   string(r.subtype),
+			Data:      r.data,
+			Timestamp: r.timestamp,
+		}
+	}
+	// Buffer even if the poll was cancelled, e.g. on shutdown.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	dropped, err := conf.BufferReadings(ctx, *config.Id, buffered, bufferMaxReadings(config), bufferMaxAge(config))
+	if err != nil {
+		return fmt.Errorf("buffering %d readings: %v", len(readings), err)
+	}
+	setBuffered(*config.Id, true)
+	for _, r := range readings {
+		remember(r.assetId, r.subtype, r.data, r.timestamp)
+	}
+	metrics.BufferedReadings(*config.Id, metrics.BufferBuffered, len(readings))
+	if dropped > 0 {
+		log.Warn("Eliona", "dropped %d buffered readings of config %d exceeding the buffer limits", dropped, *config.Id)
+		metrics.BufferedReadings(*config.Id, metrics.BufferDropped, int(dropped))
+	}
+	return nil
+}
+
+// replay writes the buffered readings to Eliona, oldest first, and reports whether the buffer was
+// emptied. It stops after half the poll interval, so that the poll holding the lock ends before
+// the next one is due; the rest is replayed by the following polls. Readings rejected by Eliona
+// are dropped.
+func replay(ctx context.Context, config apiserver.Configuration) (bool, error) {
+	if !hasBuffered(*config.Id) {
+		return true, nil
+	}
+	deadline := time.Now().Add(max(time.Duration(config.DataPollInterval)*time.Second/2, time.Second))
+	for {
+		buffered, err := conf.BufferedReadings(ctx, *config.Id, bufferMaxAge(config), replayBatchSize)
+		if err != nil {
+			return false, fmt.Errorf("%w: reading buffered readings: %v", errBufferUnavailable, err)
+		}
+		if len(buffered) == 0 {
+			setBuffered(*config.Id, false)
+			return true, nil
+		}
+		readings := make([]reading, len(buffered))
+		for i, b := range buffered {
+			readings[i] = reading{
+				bufferID:  b.ID,
+				assetId:   b.AssetID,
+				subtype:   api.DataSubtype(b.Subtype),
+				data:      b.Data,
+				timestamp: b.Timestamp,
+			}
+		}
+
+		// Probe with the oldest reading, so that a still unreachable Eliona costs a single request.
+		result := writeAll(ctx, byAsset(readings[:1]), 1)
+		if len(result.unreachable) == 0 {
+			rest := writeAll(ctx, byAsset(readings[1:]), maxConcurrentWrites)
+			result.written = append(result.written, rest.written...)
+			result.failed = append(result.failed, rest.failed...)
+			result.unreachable = append(result.unreachable, rest.unreachable...)
+			result.errs = append(result.errs, rest.errs...)
+		}
+		for range result.errs {
+			metrics.UpsertError(*config.Id)
+		}
+
+		var done []int64
+		for _, r := range append(result.written, result.failed...) {
+			done = append(done, r.bufferID)
+		}
+		if len(done) > 0 {
+			if err := conf.DeleteBufferedReadings(ctx, done); err != nil {
+				return false, fmt.Errorf("%w: deleting replayed readings: %v", errBufferUnavailable, err)
+			}
+			log.Info("Eliona", "replayed %d buffered readings of config %d", len(result.written), *config.Id)
+			metrics.BufferedReadings(*config.Id, metrics.BufferReplayed, len(result.written))
+		}
+		if len(result.failed) > 0 {
+			log.Warn("Eliona", "dropped %d buffered readings of config %d rejected by Eliona", len(result.failed), *config.Id)
+			metrics.BufferedReadings(*config.Id, metrics.BufferDropped, len(result.failed))
+		}
+		if len(result.unreachable) > 0 {
+			return false, fmt.Errorf("replaying buffered readings: %v", errors.Join(result.errs...))
+		}
+		if !time.Now().Before(deadline) {
+			log.Info("Eliona", "replaying the buffered readings of config %d continues at the next poll", *config.Id)
+			return false, nil
+		}
+	}
+}
+
+func bufferMaxReadings(config apiserver.Configuration) int32 {
+	if config.BufferMaxReadings == nil {
+		return conf.DefaultBufferMaxReadings
+	}
+	return *config.BufferMaxReadings
+}
+
+func bufferMaxAge(config apiserver.Configuration) time.Duration {
+	if config.BufferMaxAge == nil || *config.BufferMaxAge <= 0 {
+		return time.Duration(conf.DefaultBufferMaxAge) * time.Second
+	}
+	return time.Duration(*config.BufferMaxAge) * time.Second
+}
@@ -16,6 +16,7 @@
 package eliona
 
 import (
+	"context"
 	"fmt"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
@@ -23,14 +24,14 @@ import (
 	"github.com/eliona-smart-building-assistant/go-utils/common"
 )
 
-func GetDashboard(projectId string) (api.Dashboard, error) {
+func GetDashboard(ctx context.Context, projectId string) (api.Dashboard, error) {
 	dashboard := api.Dashboard{}
 	dashboard.Name = "myStrom"
 	dashboard.ProjectId = projectId
 	dashboard.Widgets = []api.Widget{}
 
 	switches, _, err := client.NewClient().AssetsAPI.
-		GetAssets(client.AuthenticationContext()).
+		GetAssets(client.AuthenticationContextWrap(ctx)).
 		AssetTypeName("mystrom_switch").
 		ProjectId(projectId).
 		Execute()
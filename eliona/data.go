@@ -2,23 +2,55 @@ package eliona
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"mystrom/apiserver"
 	"mystrom/conf"
+	"mystrom/metrics"
+	"mystrom/model"
+	"net/http"
+	"sync"
+	"time"
 
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
+	"github.com/eliona-smart-building-assistant/go-eliona/client"
+	"github.com/eliona-smart-building-assistant/go-utils/common"
 	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
 const ClientReference string = "myStrom-app"
 
-func UpsertSwitchData(config apiserver.Configuration, assets []asset.Asset) error {
-	for _, projectId := range *config.ProjectIDs {
+// maxConcurrentWrites bounds the number of assets whose data is written to Eliona at the same time.
+const maxConcurrentWrites = 8
+
+// UpsertSwitchData writes the data of the devices to their assets in all projects of the
+// configuration. Data that did not change beyond the deadbands of the configuration since it was
+// last written is only written again after the heartbeat interval. While Eliona is unreachable,
+// the data is buffered and written later. A failing asset does not stop the others from being
+// updated; the number of updated assets is returned together with all errors that occurred.
+func UpsertSwitchData(ctx context.Context, config apiserver.Configuration, assets []asset.Asset) (int, error) {
+	now := time.Now()
+	var readings []reading
+	var errs []error
+	for _, projectId := range conf.ProjIds(config) {
 		for _, a := range assets {
 			log.Debug("Eliona", "upserting data %+v for asset: config %d and asset '%v'", a, config.Id, a.GetGAI())
-			assetId, err := conf.GetAssetId(context.Background(), config, projectId, a.GetGAI())
+			if d, ok := a.(model.Device); ok {
+				in, err := model.InProject(d, config, projectId)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("checking project of %v: %v", a.GetGAI(), err))
+					continue
+				}
+				if !in {
+					continue
+				}
+			}
+			assetId, err := conf.GetAssetId(ctx, config, projectId, a.GetGAI())
 			if err != nil {
-				return err
+				errs = append(errs, fmt.Errorf("getting asset ID for %v: %v", a.GetGAI(), err))
+				continue
 			}
 			if assetId == nil {
 				// This might happen in case of filtered or newly added devices.
@@ -26,15 +58,293 @@ func UpsertSwitchData(config apiserver.Configuration, assets []asset.Asset) erro
 				continue
 			}
 
-			data := asset.Data{
-				AssetId:         *assetId,
-				Data:            a,
-				ClientReference: ClientReference,
+			pending := pendingReadings(config, *assetId, a, now)
+			if len(pending) == 0 {
+				metrics.UpsertSkipped(*config.Id)
+				continue
 			}
-			if err := asset.UpsertAssetDataIfAssetExists(data); err != nil {
-				return fmt.Errorf("upserting data: %v", err)
+			readings = append(readings, pending...)
+		}
+	}
+	updated, err := write(ctx, config, readings)
+	return updated, errors.Join(append(errs, err)...)
+}
+
+// reading is the data of one subtype of an asset at a point in time.
+type reading struct {
+	bufferID  int64 // set if the reading was buffered
+	assetId   int32
+	subtype   api.DataSubtype
+	data      map[string]interface{}
+	timestamp time.Time
+}
+
+// pendingReadings returns the data of the asset that has to be written. It is timestamped with
+// the time the myStrom response holding it was received, or now if that is unknown.
+func pendingReadings(config apiserver.Configuration, assetId int32, a asset.Asset, now time.Time) []reading {
+	timestamp := now
+	if d, ok := a.(model.Device); ok && !d.GetMeasuredAt().IsZero() {
+		timestamp = d.GetMeasuredAt()
+	}
+	var readings []reading
+	for subtype, data := range changedSubtypes(config, assetId, asset.SplitBySubtype(a), now) {
+		readings = append(readings, reading{assetId: assetId, subtype: subtype, data: data, timestamp: timestamp})
+	}
+	return readings
+}
+
+// byAsset groups the readings by asset, keeping their order.
+func byAsset(readings []reading) [][]reading {
+	var groups [][]reading
+	index := make(map[int32]int)
+	for _, r := range readings {
+		i, ok := index[r.assetId]
+		if !ok {
+			i = len(groups)
+			index[r.assetId] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups
+}
+
+type writeResult struct {
+	written     []reading
+	failed      []reading // rejected by Eliona or of deleted assets, not worth writing again
+	unreachable []reading // not written because Eliona was unreachable, worth writing later
+	errs        []error
+}
+
+// assets returns the number of assets with data written.
+func (r writeResult) assets() int {
+	assets := make(map[int32]bool)
+	for _, w := range r.written {
+		assets[w.assetId] = true
+	}
+	return len(assets)
+}
+
+// writeAll writes the groups of readings using the given number of workers. The readings of a
+// group are written in order. Once Eliona turns out to be unreachable, the rest of the group is
+// not attempted.
+func writeAll(ctx context.Context, groups [][]reading, workers int) writeResult {
+	var (
+		mu     sync.Mutex
+		result writeResult
+		wg     sync.WaitGroup
+	)
+	c := client.NewClient()
+	queue := make(chan []reading)
+	for range min(workers, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for i, r := range group {
+					err := writeReading(ctx, c, r)
+					mu.Lock()
+					switch {
+					case err == nil:
+						result.written = append(result.written, r)
+					case errors.Is(err, errAssetDeleted):
+						result.failed = append(result.failed, r)
+					case errors.Is(err, errUnreachable):
+						result.unreachable = append(result.unreachable, group[i:]...)
+						result.errs = append(result.errs, err)
+					default:
+						result.failed = append(result.failed, r)
+						result.errs = append(result.errs, err)
+					}
+					mu.Unlock()
+					if errors.Is(err, errUnreachable) {
+						break
+					}
+				}
 			}
+		}()
+	}
+	for _, group := range groups {
+		queue <- group
+	}
+	close(queue)
+	wg.Wait()
+	return result
+}
+
+var (
+	// errUnreachable marks failures caused by Eliona not answering or failing itself.
+	errUnreachable = errors.New("Eliona unreachable")
+
+	errAssetDeleted = errors.New("asset deleted in Eliona")
+)
+
+// writeReading writes the reading with its original timestamp. Like
+// asset.UpsertAssetDataIfAssetExists, but bound to the context.
+func writeReading(ctx context.Context, c *api.APIClient, r reading) error {
+	assetType, err := getAssetType(ctx, c, r.assetId)
+	if err != nil {
+		return err
+	}
+	res, err := c.DataAPI.
+		PutData(client.AuthenticationContextWrap(ctx)).
+		Data(api.Data{
+			AssetId:         r.assetId,
+			Subtype:         r.subtype,
+			Timestamp:       *api.NewNullableTime(&r.timestamp),
+			Data:            r.data,
+			AssetTypeName:   *api.NewNullableString(&assetType),
+			ClientReference: *api.NewNullableString(common.Ptr(ClientReference)),
+		}).
+		Execute()
+	if err != nil {
+		// The asset might have been deleted in Eliona. Check again next time.
+		forgetAssetType(r.assetId)
+		if unreachable(res) {
+			return fmt.Errorf("%w: upserting data for asset id %v and subtype %s: %v", errUnreachable, r.assetId, r.subtype, err)
 		}
+		return fmt.Errorf("upserting data for asset id %v and subtype %s: %v", r.assetId, r.subtype, err)
 	}
 	return nil
 }
+
+func unreachable(res *http.Response) bool {
+	return res == nil || res.StatusCode >= http.StatusInternalServerError
+}
+
+// The asset types of the assets written to, as the type of an asset never changes.
+var (
+	assetTypesMu sync.Mutex
+	assetTypes   = make(map[int32]string)
+)
+
+// getAssetType returns the asset type of the asset, or errAssetDeleted if it does not exist in
+// Eliona.
+func getAssetType(ctx context.Context, c *api.APIClient, assetId int32) (string, error) {
+	assetTypesMu.Lock()
+	assetType, ok := assetTypes[assetId]
+	assetTypesMu.Unlock()
+	if ok {
+		return assetType, nil
+	}
+	elionaAsset, res, err := c.AssetsAPI.
+		GetAssetById(client.AuthenticationContextWrap(ctx), assetId).
+		Execute()
+	if res != nil && res.StatusCode == http.StatusNotFound {
+		return "", errAssetDeleted
+	}
+	if err != nil && unreachable(res) {
+		return "", fmt.Errorf("%w: getting asset id %v: %v", errUnreachable, assetId, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting asset id %v: %v", assetId, err)
+	}
+	assetTypesMu.Lock()
+	assetTypes[assetId] = elionaAsset.AssetType
+	assetTypesMu.Unlock()
+	return elionaAsset.AssetType, nil
+}
+
+func forgetAssetType(assetId int32) {
+	assetTypesMu.Lock()
+	defer assetTypesMu.Unlock()
+	delete(assetTypes, assetId)
+}
+
+// The data last written to each asset by subtype, to skip writing unchanged data.
+var (
+	writtenMu sync.Mutex
+	written   = make(map[int32]map[api.DataSubtype]writtenData)
+)
+
+type writtenData struct {
+	data map[string]interface{}
+	at   time.Time
+}
+
+// changedSubtypes returns the data of the subtypes that have to be written: those never written,
+// those with a value that changed beyond its deadband, and all of them once the heartbeat interval
+// passed.
+func changedSubtypes(config apiserver.Configuration, assetId int32, data map[api.DataSubtype]map[string]interface{}, now time.Time) map[api.DataSubtype]map[string]interface{} {
+	heartbeat := time.Duration(conf.DefaultHeartbeatInterval) * time.Second
+	if config.HeartbeatInterval != nil {
+		heartbeat = time.Duration(*config.HeartbeatInterval) * time.Second
+	}
+	writtenMu.Lock()
+	defer writtenMu.Unlock()
+	changes := make(map[api.DataSubtype]map[string]interface{})
+	for subtype, subData := range data {
+		last, ok := written[assetId][subtype]
+		if !ok || now.Sub(last.at) >= heartbeat || changed(config, last.data, subData) {
+			changes[subtype] = subData
+		}
+	}
+	return changes
+}
+
+func changed(config apiserver.Configuration, last, data map[string]interface{}) bool {
+	for name, value := range data {
+		lastValue, ok := last[name]
+		if !ok {
+			return true
+		}
+		v, vOk := number(value)
+		l, lOk := number(lastValue)
+		if !vOk || !lOk {
+			if value != lastValue {
+				return true
+			}
+			continue
+		}
+		if math.Abs(v-l) > deadband(config, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// deadband returns the change of the attribute that is not worth writing.
+func deadband(config apiserver.Configuration, attribute string) float64 {
+	switch attribute {
+	case "power":
+		return float64(max(common.Val(config.PowerDeadband), 0))
+	case "temperature":
+		return float64(max(common.Val(config.TemperatureDeadband), 0))
+	default:
+		return 0
+	}
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func remember(assetId int32, subtype api.DataSubtype, data map[string]interface{}, at time.Time) {
+	writtenMu.Lock()
+	defer writtenMu.Unlock()
+	if written[assetId] == nil {
+		written[assetId] = make(map[api.DataSubtype]writtenData)
+	}
+	written[assetId][subtype] = writtenData{data: data, at: at}
+}
+
+// ForgetWritten makes the next poll write all data of the asset, e.g. after an output value was
+// set in Eliona that the device may not have taken over.
+func ForgetWritten(assetId int32) {
+	writtenMu.Lock()
+	defer writtenMu.Unlock()
+	delete(written, assetId)
+}
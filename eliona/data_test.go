@@ -0,0 +1,196 @@
+package eliona
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"mystrom/apiserver"
+	"mystrom/appdb"
+	"mystrom/conf"
+	"mystrom/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// fakeElionaLatency is the time the fake Eliona API takes to answer a request.
+const fakeElionaLatency = 2 * time.Millisecond
+
+func fakeEliona(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(fakeElionaLatency)
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/assets/"):
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": 1, "projectId": "1", "globalAssetIdentifier": "mystrom_switch_1", "assetType": "mystrom_switch"}`)
+	case r.Method == http.MethodPut && r.URL.Path == "/data":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fakeDatabaseLatency is the time the fake database takes to answer a query.
+const fakeDatabaseLatency = 500 * time.Microsecond
+
+// benchmarkDevices is the number of switches written per benchmark iteration.
+const benchmarkDevices = 100
+
+func init() {
+	sql.Register("fakeassets", fakeAssetsDriver{})
+}
+
+// fakeAssetsDriver answers every query with asset mappings of the benchmark switches: the one
+// asked for if a GAI is among the arguments, all of them otherwise.
+type fakeAssetsDriver struct{}
+
+func (fakeAssetsDriver) Open(string) (driver.Conn, error) { return fakeAssetsConn{}, nil }
+
+type fakeAssetsConn struct{}
+
+func (fakeAssetsConn) Prepare(query string) (driver.Stmt, error) { return fakeAssetsStmt{}, nil }
+func (fakeAssetsConn) Close() error                              { return nil }
+func (fakeAssetsConn) Begin() (driver.Tx, error)                 { return nil, errNotSupported }
+
+type fakeAssetsStmt struct{}
+
+func (fakeAssetsStmt) Close() error  { return nil }
+func (fakeAssetsStmt) NumInput() int { return -1 }
+func (fakeAssetsStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errNotSupported
+}
+
+var errNotSupported = errors.New("not supported by the fake database")
+
+func (fakeAssetsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	time.Sleep(fakeDatabaseLatency)
+	rows := &fakeAssetsRows{}
+	for i := range benchmarkDevices {
+		gai := (&model.Switch{ID: fmt.Sprintf("switch%d", i)}).GetGAI()
+		if wanted, ok := gaiArgument(args); ok && wanted != gai {
+			continue
+		}
+		rows.values = append(rows.values, []driver.Value{int64(i + 1), int64(1), "1", gai, fmt.Sprintf("switch%d", i), int64(i + 1), false})
+	}
+	return rows, nil
+}
+
+func gaiArgument(args []driver.Value) (string, bool) {
+	for _, arg := range args {
+		if s, ok := arg.(string); ok && strings.HasPrefix(s, "mystrom_switch_") {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+type fakeAssetsRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeAssetsRows) Columns() []string {
+	return []string{
+		appdb.AssetColumns.ID,
+		appdb.AssetColumns.ConfigurationID,
+		appdb.AssetColumns.ProjectID,
+		appdb.AssetColumns.GlobalAssetID,
+		appdb.AssetColumns.ProviderID,
+		appdb.AssetColumns.AssetID,
+		appdb.AssetColumns.Pinned,
+	}
+}
+
+func (r *fakeAssetsRows) Close() error { return nil }
+
+func (r *fakeAssetsRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// uncachedAssetId looks up the asset ID the way it was done before the asset IDs were cached:
+// one query per device.
+func uncachedAssetId(ctx context.Context, config apiserver.Configuration, projId string, globalAssetID string) (*int32, error) {
+	dbAsset, err := appdb.Assets(
+		appdb.AssetWhere.ConfigurationID.EQ(*config.Id),
+		appdb.AssetWhere.ProjectID.EQ(projId),
+		appdb.AssetWhere.GlobalAssetID.EQ(globalAssetID),
+		qm.OrderBy(appdb.AssetColumns.Pinned+" desc"),
+	).AllG(ctx)
+	if err != nil || len(dbAsset) == 0 {
+		return nil, err
+	}
+	return common.Ptr(dbAsset[0].AssetID.Int32), nil
+}
+
+// BenchmarkWriteAll looks up the asset IDs of 100 switches in a fake database and writes their
+// data to a fake Eliona API: the way it was done before (one query and one asset after the other,
+// fetching the asset type every time), one asset after the other with cached asset IDs and
+// types, and with the worker pool.
+func BenchmarkWriteAll(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(fakeEliona))
+	defer server.Close()
+	b.Setenv("API_ENDPOINT", server.URL)
+	database, err := sql.Open("fakeassets", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer database.Close()
+	boil.SetDB(database)
+
+	// A heartbeat interval of 0 writes all data every time.
+	config := apiserver.Configuration{Id: common.Ptr(int64(1)), HeartbeatInterval: common.Ptr(int32(0))}
+	switches := make([]*model.Switch, benchmarkDevices)
+	for i := range switches {
+		switches[i] = &model.Switch{ID: fmt.Sprintf("switch%d", i), Type: "ws2", Power: 12.5, Temp: 21.3, Relay: 1}
+	}
+
+	for _, bc := range []struct {
+		name    string
+		workers int
+		cached  bool
+	}{
+		{"serial-uncached", 1, false},
+		{"serial", 1, true},
+		{"pool", maxConcurrentWrites, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx := context.Background()
+			getAssetId := conf.GetAssetId
+			if !bc.cached {
+				getAssetId = uncachedAssetId
+			}
+			conf.ForgetAssetIds(*config.Id)
+			for b.Loop() {
+				if !bc.cached {
+					assetTypesMu.Lock()
+					clear(assetTypes)
+					assetTypesMu.Unlock()
+				}
+				var readings []reading
+				for _, s := range switches {
+					assetId, err := getAssetId(ctx, config, "1", s.GetGAI())
+					if err != nil || assetId == nil {
+						b.Fatalf("getting asset ID of %v: %v", s.GetGAI(), err)
+					}
+					readings = append(readings, pendingReadings(config, *assetId, s, time.Now())...)
+				}
+				result := writeAll(ctx, byAsset(readings), bc.workers)
+				if len(result.errs) > 0 || result.assets() != len(switches) {
+					b.Fatalf("updated %d of %d assets: %v", result.assets(), len(switches), result.errs)
+				}
+			}
+		})
+	}
+}
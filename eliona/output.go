@@ -1,7 +1,7 @@
 package eliona
 
 import (
-	"time"
+	"context"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
 	"github.com/eliona-smart-building-assistant/go-utils/common"
@@ -9,11 +9,31 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// ListenForOutputChanges on assets (only output attributes). Returns a channel with all changes.
-func ListenForOutputChanges() (chan api.Data, error) {
+// ListenForOutputChanges on assets (only output attributes). Returns a channel with all changes,
+// which is closed when the connection breaks or the context is cancelled. The reason the
+// connection ended is sent to the error channel afterwards, nil if it was closed normally.
+func ListenForOutputChanges(ctx context.Context) (<-chan api.Data, <-chan error, error) {
+	conn, err := newWebsocket()
+	if err != nil {
+		return nil, nil, err
+	}
 	outputs := make(chan api.Data)
-	go http.ListenWebSocketWithReconnectAlways(newWebsocket, time.Duration(0), outputs)
-	return outputs, nil
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.Close()
+	}()
+	go func() {
+		err := http.ListenWebSocket(conn, outputs)
+		close(done)
+		errs <- err
+		close(outputs)
+	}()
+	return outputs, errs, nil
 }
 
 func newWebsocket() (*websocket.Conn, error) {
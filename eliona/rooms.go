@@ -0,0 +1,181 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package eliona
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/eliona-smart-building-assistant/go-eliona/client"
+)
+
+const (
+	maxRoomCandidates = 3
+	minRoomScore      = 0.5
+)
+
+// RoomCandidate is an existing Eliona asset that might represent a myStrom room.
+type RoomCandidate struct {
+	AssetID   int32
+	Name      string
+	AssetType string
+
+	// Score is the similarity of the names between 0 and 1.
+	Score float64
+}
+
+// roomTypeKeywords identify the asset types describing rooms and floors by their name or
+// translation, as Eliona does not categorize asset types.
+var roomTypeKeywords = []string{"room", "floor", "storey", "level", "space", "raum", "stockwerk", "geschoss", "etage", "zimmer"}
+
+// SuggestRooms returns the Eliona assets of the project whose names are most similar to the names
+// of the myStrom rooms, best match first. Only rooms and floors are suggested, and not the assets
+// created by this app.
+func SuggestRooms(ctx context.Context, projectId string, rooms map[string]string) (map[string][]RoomCandidate, error) {
+	roomTypes, err := roomAssetTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assets, _, err := client.NewClient().AssetsAPI.
+		GetAssets(client.AuthenticationContextWrap(ctx)).
+		ProjectId(projectId).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("fetching assets of project %v: %v", projectId, err)
+	}
+
+	suggestions := make(map[string][]RoomCandidate)
+	for roomID, roomName := range rooms {
+		var candidates []RoomCandidate
+		for _, a := range assets {
+			if !roomTypes[a.AssetType] || !a.Id.IsSet() || a.Id.Get() == nil {
+				continue
+			}
+			name := a.Name.Get()
+			if name == nil {
+				continue
+			}
+			score := nameSimilarity(roomName, *name)
+			if score < minRoomScore {
+				continue
+			}
+			candidates = append(candidates, RoomCandidate{
+				AssetID:   *a.Id.Get(),
+				Name:      *name,
+				AssetType: a.AssetType,
+				Score:     score,
+			})
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Score > candidates[j].Score
+		})
+		if len(candidates) > maxRoomCandidates {
+			candidates = candidates[:maxRoomCandidates]
+		}
+		suggestions[roomID] = candidates
+	}
+	return suggestions, nil
+}
+
+// roomAssetTypes returns the names of the asset types describing rooms and floors, leaving out the
+// asset types of this app.
+func roomAssetTypes(ctx context.Context) (map[string]bool, error) {
+	types, _, err := client.NewClient().AssetTypesAPI.
+		GetAssetTypes(client.AuthenticationContextWrap(ctx)).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("fetching asset types: %v", err)
+	}
+	roomTypes := make(map[string]bool)
+	for _, t := range types {
+		if strings.HasPrefix(t.Name, "mystrom_") {
+			continue
+		}
+		names := []string{t.Name}
+		if tr, ok := t.GetTranslationOk(); ok && tr != nil {
+			for _, n := range []*string{tr.En, tr.De, tr.Fr, tr.It} {
+				if n != nil {
+					names = append(names, *n)
+				}
+			}
+		}
+		if describesRoom(names) {
+			roomTypes[t.Name] = true
+		}
+	}
+	return roomTypes, nil
+}
+
+func describesRoom(names []string) bool {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		for _, keyword := range roomTypeKeywords {
+			if strings.Contains(name, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nameSimilarity compares two names ignoring case, punctuation and whitespace. Identical names
+// score 1; a name contained in the other scores at least 0.8.
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	score := 1 - float64(levenshtein(ra, rb))/float64(longest)
+	if strings.Contains(a, b) || strings.Contains(b, a) {
+		score = max(score, 0.8)
+	}
+	return score
+}
+
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
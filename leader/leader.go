@@ -0,0 +1,170 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package leader elects a single replica of the app that collects data and acts on output
+// changes. Leadership is held as a PostgreSQL session-level advisory lock on a dedicated
+// connection, so it is released by the database as soon as the leader's session ends.
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+const (
+	// lockName identifies the advisory lock among the locks of other apps using the database.
+	lockName = "mystrom.leader"
+
+	// checkInterval is how often a standby tries to take the lock and the leader checks that
+	// its session is still alive.
+	checkInterval = 5 * time.Second
+
+	// checkTimeout bounds each query, so that a leader that lost its database connection steps
+	// down within checkInterval + checkTimeout.
+	checkTimeout = 3 * time.Second
+
+	// keepaliveSeconds makes the database notice a dead leader session and release its lock
+	// after about 3 * keepaliveSeconds, which happens after the leader stepped down on its own.
+	keepaliveSeconds = 5
+)
+
+var (
+	leading atomic.Bool
+
+	connMu sync.Mutex
+	conn   *sql.Conn
+)
+
+// IsLeader reports whether this replica holds the leadership.
+func IsLeader() bool {
+	return leading.Load()
+}
+
+// Campaign tries to become the leader and keeps the leadership until the context is cancelled or
+// the connection holding the lock fails. Leadership is not given up on cancellation, so that the
+// leader can finish its work first. Call Resign after that.
+func Campaign(ctx context.Context, database *sql.DB) {
+	for ctx.Err() == nil {
+		if err := campaign(ctx, database); err != nil {
+			log.Error("leader", "campaigning for leadership: %v", err)
+		}
+		sleep(ctx, checkInterval)
+	}
+}
+
+// Resign gives up the leadership by ending the session holding the lock.
+func Resign() {
+	connMu.Lock()
+	defer connMu.Unlock()
+	if conn == nil {
+		return
+	}
+	if leading.Swap(false) {
+		log.Info("leader", "Resigning leadership.")
+	}
+	discard(conn)
+	conn = nil
+}
+
+func campaign(ctx context.Context, database *sql.DB) error {
+	connMu.Lock()
+	var err error
+	if conn == nil {
+		conn, err = open(ctx, database)
+	}
+	c := conn
+	connMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		if leading.Load() {
+			err = exec(c, "select 1")
+		} else {
+			err = tryLock(c)
+		}
+		if err != nil {
+			Resign()
+			return err
+		}
+		sleep(ctx, checkInterval)
+	}
+	return nil
+}
+
+// open returns a connection of its own, so that the lock is not shared with pooled queries.
+func open(ctx context.Context, database *sql.DB) (*sql.Conn, error) {
+	c, err := database.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("opening connection: %v", err)
+	}
+	for _, setting := range []string{"tcp_keepalives_idle", "tcp_keepalives_interval"} {
+		if err := exec(c, fmt.Sprintf("set %s = %d", setting, keepaliveSeconds)); err != nil {
+			discard(c)
+			return nil, err
+		}
+	}
+	if err := exec(c, "set tcp_keepalives_count = 3"); err != nil {
+		discard(c)
+		return nil, err
+	}
+	return c, nil
+}
+
+func tryLock(c *sql.Conn) error {
+	// Not bound to the campaign context: cancelling a query may end the session and with it the
+	// leadership, which must be kept until Resign.
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	var locked bool
+	if err := c.QueryRowContext(ctx, "select pg_try_advisory_lock(hashtext($1))", lockName).Scan(&locked); err != nil {
+		return fmt.Errorf("taking advisory lock: %v", err)
+	}
+	if locked {
+		leading.Store(true)
+		log.Info("leader", "Took over leadership.")
+	}
+	return nil
+}
+
+func exec(c *sql.Conn, query string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	if _, err := c.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("executing %q: %v", query, err)
+	}
+	return nil
+}
+
+// discard closes the session instead of returning it to the pool, which releases the lock.
+func discard(c *sql.Conn) {
+	_ = c.Raw(func(any) error { return driver.ErrBadConn })
+	_ = c.Close()
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
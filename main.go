@@ -16,6 +16,13 @@
 package main
 
 import (
+	"context"
+	"mystrom/collector"
+	"mystrom/conf"
+	"mystrom/leader"
+	"mystrom/output"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/app"
@@ -25,11 +32,18 @@ import (
 	"github.com/volatiletech/sqlboiler/v4/boil"
 )
 
+// shutdownTimeout bounds the cleanup after the app was signalled to terminate.
+const shutdownTimeout = 10 * time.Second
+
 // The main function starts the app by starting all services necessary for this app and waits
-// until all services are finished.
+// until all services are finished. On termination, every in-flight request is cancelled, all
+// configurations are marked inactive and the leadership is handed over to a standby replica.
 func main() {
 	log.Info("main", "Starting the app.")
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT)
+	defer stop()
+
 	// Set default database to use boil.*G functions.
 	database := db.Database(app.AppName())
 	defer database.Close()
@@ -41,14 +55,26 @@ func main() {
 		boil.DebugWriter = log.GetWriter(log.TraceLevel, "database")
 	}
 
-	initialize()
+	initialize(ctx)
+	collector.Init(ctx)
 
 	// Starting the service to collect the data for this app.
-	common.WaitForWithOs(
-		common.Loop(collectData, time.Second),
-		listenApi,
-		listenForOutputChanges,
+	common.WaitFor(
+		func() { loop(ctx, collectData, time.Second) },
+		func() { listenApi(ctx) },
+		func() { output.Listen(ctx, handleOutput) },
+		func() { leader.Campaign(ctx, database) },
 	)
 
 	log.Info("main", "Terminate the app.")
+	collector.StopAll()
+	if leader.IsLeader() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if _, err := conf.SetAllConfigsInactive(cleanupCtx); err != nil {
+			log.Error("conf", "setting all configs inactive: %v", err)
+		}
+	}
+	// Only now a standby may take over.
+	leader.Resign()
 }
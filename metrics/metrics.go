@@ -0,0 +1,243 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Endpoints of the myStrom API used as label values.
+const (
+	EndpointDevicesV1      = "v1_devices"
+	EndpointDevicesV2      = "v2_devices"
+	EndpointDeviceV2Action = "v2_device_action"
+)
+
+const namespace = "mystrom"
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Requests to the myStrom API by endpoint, configuration and status code. The status is 0 if no response was received.",
+	}, []string{"endpoint", "config", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of requests to the myStrom API by endpoint and configuration.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "config"})
+
+	pollDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration_seconds",
+		Help:      "Duration of data polls by configuration, including writing the data to Eliona.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"config"})
+
+	devices = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "devices",
+		Help:      "Devices reported by myStrom at the last discovery by configuration and device type.",
+	}, []string{"config", "type"})
+
+	upsertErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "eliona_upsert_errors_total",
+		Help:      "Failed writes of device data to Eliona by configuration.",
+	}, []string{"config"})
+
+	upsertsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "eliona_upserts_skipped_total",
+		Help:      "Device data not written to Eliona because it did not change since it was last written.",
+	}, []string{"config"})
+
+	bufferedReadings = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "eliona_buffered_readings_total",
+		Help:      "Device data buffered while Eliona was unreachable by configuration and action: buffered, replayed or dropped.",
+	}, []string{"config", "action"})
+
+	outputCommands = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "output_commands_total",
+		Help:      "Output changes received from Eliona, excluding echoes of values sent by the app.",
+	})
+
+	echoesSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "output_echoes_skipped_total",
+		Help:      "Output changes received from Eliona that were echoes of values sent by the app.",
+	})
+
+	websocketReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_reconnects_total",
+		Help:      "Reconnects of the websocket listening for output changes.",
+	})
+
+	outputListenerConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "output_listener_connected",
+		Help:      "1 if the app is connected to Eliona to listen for output changes, 0 otherwise.",
+	})
+
+	outputCommandsSuperseded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "output_commands_superseded_total",
+		Help:      "Output changes not passed to the device because a newer change of the same device arrived first.",
+	})
+
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_cache_hits_total",
+		Help:      "Requests to the myStrom API answered by a response shared with another configuration of the same account.",
+	}, []string{"endpoint"})
+
+	requestsDeferred = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_deferred_total",
+		Help:      "Requests to the myStrom API deferred because the rate limit of the account was reached.",
+	}, []string{"api"})
+
+	requestRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_request_retries_total",
+		Help:      "Requests to the myStrom API repeated after myStrom throttled or failed them.",
+	}, []string{"api", "status"})
+
+	leading = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "1 if this replica holds the leadership and collects data, 0 if it is on standby.",
+	})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records a request to the myStrom API. A configuration that was not saved yet has
+// no ID.
+func ObserveRequest(endpoint string, configID *int64, statusCode int, duration time.Duration) {
+	config := configLabel(configID)
+	requests.WithLabelValues(endpoint, config, strconv.Itoa(statusCode)).Inc()
+	requestDuration.WithLabelValues(endpoint, config).Observe(duration.Seconds())
+}
+
+func ObservePoll(configID int64, duration time.Duration) {
+	pollDuration.WithLabelValues(configLabel(&configID)).Observe(duration.Seconds())
+}
+
+// SetDevices replaces the number of devices per type of the configuration.
+func SetDevices(configID int64, byType map[string]int) {
+	config := configLabel(&configID)
+	devices.DeletePartialMatch(prometheus.Labels{"config": config})
+	for deviceType, count := range byType {
+		devices.WithLabelValues(config, deviceType).Set(float64(count))
+	}
+}
+
+func UpsertError(configID int64) {
+	upsertErrors.WithLabelValues(configLabel(&configID)).Inc()
+}
+
+func UpsertSkipped(configID int64) {
+	upsertsSkipped.WithLabelValues(configLabel(&configID)).Inc()
+}
+
+// Actions on buffered device data used as label values.
+const (
+	BufferBuffered = "buffered"
+	BufferReplayed = "replayed"
+	BufferDropped  = "dropped"
+)
+
+func BufferedReadings(configID int64, action string, count int) {
+	bufferedReadings.WithLabelValues(configLabel(&configID), action).Add(float64(count))
+}
+
+func OutputCommand() {
+	outputCommands.Inc()
+}
+
+func EchoSkipped() {
+	echoesSkipped.Inc()
+}
+
+func WebsocketReconnect() {
+	websocketReconnects.Inc()
+}
+
+func CacheHit(endpoint string) {
+	cacheHits.WithLabelValues(endpoint).Inc()
+}
+
+func RequestDeferred(api string) {
+	requestsDeferred.WithLabelValues(api).Inc()
+}
+
+func RequestRetried(api string, status int) {
+	requestRetries.WithLabelValues(api, strconv.Itoa(status)).Inc()
+}
+
+func SetOutputListenerConnected(connected bool) {
+	if connected {
+		outputListenerConnected.Set(1)
+	} else {
+		outputListenerConnected.Set(0)
+	}
+}
+
+func OutputCommandSuperseded() {
+	outputCommandsSuperseded.Inc()
+}
+
+func SetLeader(leader bool) {
+	if leader {
+		leading.Set(1)
+	} else {
+		leading.Set(0)
+	}
+}
+
+// Forget removes the metrics of a deleted configuration.
+func Forget(configID int64) {
+	labels := prometheus.Labels{"config": configLabel(&configID)}
+	requests.DeletePartialMatch(labels)
+	requestDuration.DeletePartialMatch(labels)
+	pollDuration.DeletePartialMatch(labels)
+	devices.DeletePartialMatch(labels)
+	upsertErrors.DeletePartialMatch(labels)
+	upsertsSkipped.DeletePartialMatch(labels)
+	bufferedReadings.DeletePartialMatch(labels)
+}
+
+func configLabel(configID *int64) string {
+	if configID == nil {
+		return "unsaved"
+	}
+	return strconv.FormatInt(*configID, 10)
+}
@@ -20,41 +20,90 @@ import (
 	"fmt"
 	"mystrom/apiserver"
 	"mystrom/conf"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
 	"github.com/eliona-smart-building-assistant/go-eliona/utils"
 	"github.com/eliona-smart-building-assistant/go-utils/common"
 )
 
+// Device is a supported myStrom device represented in the asset structure.
+type Device interface {
+	asset.LocationalNode
+	asset.FunctionalNode
+	AdheresToFilter(config apiserver.Configuration) (bool, error)
+	GetID() string
+	GetDeviceName() string
+	GetType() string
+	GetRoomID() string
+	GetRoomName() string
+	SetRoom(id, name string)
+	GetMeasuredAt() time.Time
+}
+
 type Switch struct {
-	ID   string `eliona:"id"`
+	ID   string `eliona:"id,filterable"`
 	Name string `eliona:"name,filterable"`
 
+	Type     string `eliona:"type,filterable"`
+	RoomID   string `eliona:"room_id,filterable"`
+	RoomName string `eliona:"room_name,filterable"`
+
 	Power float32 `eliona:"power" subtype:"input"`
 	Temp  float32 `eliona:"temperature" subtype:"input"`
 
 	Relay int `eliona:"relay" subtype:"output"`
 
+	// MeasuredAt is when the app received the myStrom response holding the data, as myStrom does
+	// not report when it was measured. Zero if unknown.
+	MeasuredAt time.Time
+
 	Config *apiserver.Configuration
+
+	// Context bounds the database queries of GetAssetID and SetAssetID, which are called through
+	// the context-less asset interface of go-eliona.
+	Context context.Context
 }
 
-func (s *Switch) AdheresToFilter(filter [][]apiserver.FilterRule) (bool, error) {
-	f := apiFilterToCommonFilter(filter)
-	fp, err := utils.StructToMap(s)
-	if err != nil {
-		return false, fmt.Errorf("converting strict to map: %v", err)
-	}
-	adheres, err := common.Filter(f, fp)
-	if err != nil {
-		return false, err
-	}
-	return adheres, nil
+func (s *Switch) AdheresToFilter(config apiserver.Configuration) (bool, error) {
+	return adheresToFilter(s, s.ID, config)
 }
 
-func (s *Switch) GetName() string {
+func (s *Switch) GetID() string {
+	return s.ID
+}
+
+func (s *Switch) GetDeviceName() string {
 	return s.Name
 }
 
+func (s *Switch) GetType() string {
+	return s.Type
+}
+
+func (s *Switch) GetRoomID() string {
+	return s.RoomID
+}
+
+func (s *Switch) GetRoomName() string {
+	return s.RoomName
+}
+
+func (s *Switch) SetRoom(id, name string) {
+	s.RoomID = id
+	s.RoomName = name
+}
+
+func (s *Switch) GetMeasuredAt() time.Time {
+	return s.MeasuredAt
+}
+
+func (s *Switch) GetName() string {
+	return assetName(s.Config, s.Name, s.RoomName, s.Type, s.ID)
+}
+
 func (s *Switch) GetDescription() string {
 	return ""
 }
@@ -68,11 +117,11 @@ func (s *Switch) GetGAI() string {
 }
 
 func (s *Switch) GetAssetID(projectID string) (*int32, error) {
-	return conf.GetAssetId(context.Background(), *s.Config, projectID, s.GetGAI())
+	return conf.GetAssetId(orBackground(s.Context), *s.Config, projectID, s.GetGAI())
 }
 
 func (s *Switch) SetAssetID(assetID int32, projectID string) error {
-	if err := conf.InsertAsset(context.Background(), *s.Config, projectID, s.GetGAI(), assetID, s.ID); err != nil {
+	if err := conf.InsertAsset(orBackground(s.Context), *s.Config, projectID, s.GetGAI(), assetID, s.ID); err != nil {
 		return fmt.Errorf("inserting asset to Config db: %v", err)
 	}
 	return nil
@@ -87,31 +136,62 @@ func (s *Switch) GetFunctionalChildren() []asset.FunctionalNode {
 }
 
 type SwitchZero struct {
-	ID   string `eliona:"id"`
+	ID   string `eliona:"id,filterable"`
 	Name string `eliona:"name,filterable"`
 
+	Type     string `eliona:"type,filterable"`
+	RoomID   string `eliona:"room_id,filterable"`
+	RoomName string `eliona:"room_name,filterable"`
+
 	Relay int `eliona:"relay" subtype:"output"`
 
+	// MeasuredAt is when the app received the myStrom response holding the data, as myStrom does
+	// not report when it was measured. Zero if unknown.
+	MeasuredAt time.Time
+
 	Config *apiserver.Configuration
+
+	// Context bounds the database queries of the asset ID, see Switch.
+	Context context.Context
 }
 
-func (s *SwitchZero) AdheresToFilter(filter [][]apiserver.FilterRule) (bool, error) {
-	f := apiFilterToCommonFilter(filter)
-	fp, err := utils.StructToMap(s)
-	if err != nil {
-		return false, fmt.Errorf("converting strict to map: %v", err)
-	}
-	adheres, err := common.Filter(f, fp)
-	if err != nil {
-		return false, err
-	}
-	return adheres, nil
+func (s *SwitchZero) AdheresToFilter(config apiserver.Configuration) (bool, error) {
+	return adheresToFilter(s, s.ID, config)
 }
 
-func (s *SwitchZero) GetName() string {
+func (s *SwitchZero) GetID() string {
+	return s.ID
+}
+
+func (s *SwitchZero) GetDeviceName() string {
 	return s.Name
 }
 
+func (s *SwitchZero) GetType() string {
+	return s.Type
+}
+
+func (s *SwitchZero) GetRoomID() string {
+	return s.RoomID
+}
+
+func (s *SwitchZero) GetRoomName() string {
+	return s.RoomName
+}
+
+func (s *SwitchZero) SetRoom(id, name string) {
+	s.RoomID = id
+	s.RoomName = name
+}
+
+func (s *SwitchZero) GetMeasuredAt() time.Time {
+	return s.MeasuredAt
+}
+
+func (s *SwitchZero) GetName() string {
+	return assetName(s.Config, s.Name, s.RoomName, s.Type, s.ID)
+}
+
 func (s *SwitchZero) GetDescription() string {
 	return ""
 }
@@ -125,11 +205,11 @@ func (s *SwitchZero) GetGAI() string {
 }
 
 func (s *SwitchZero) GetAssetID(projectID string) (*int32, error) {
-	return conf.GetAssetId(context.Background(), *s.Config, projectID, s.GetGAI())
+	return conf.GetAssetId(orBackground(s.Context), *s.Config, projectID, s.GetGAI())
 }
 
 func (s *SwitchZero) SetAssetID(assetID int32, projectID string) error {
-	if err := conf.InsertAsset(context.Background(), *s.Config, projectID, s.GetGAI(), assetID, s.ID); err != nil {
+	if err := conf.InsertAsset(orBackground(s.Context), *s.Config, projectID, s.GetGAI(), assetID, s.ID); err != nil {
 		return fmt.Errorf("inserting asset to Config db: %v", err)
 	}
 	return nil
@@ -147,7 +227,8 @@ type Room struct {
 	ID   string
 	Name string
 
-	Config *apiserver.Configuration
+	Config  *apiserver.Configuration
+	Context context.Context
 
 	Switches []asset.LocationalNode
 }
@@ -169,11 +250,11 @@ func (r *Room) GetGAI() string {
 }
 
 func (r *Room) GetAssetID(projectID string) (*int32, error) {
-	return conf.GetAssetId(context.Background(), *r.Config, projectID, r.GetGAI())
+	return conf.GetAssetId(orBackground(r.Context), *r.Config, projectID, r.GetGAI())
 }
 
 func (r *Room) SetAssetID(assetID int32, projectID string) error {
-	if err := conf.InsertAsset(context.Background(), *r.Config, projectID, r.GetGAI(), assetID, r.ID); err != nil {
+	if err := conf.InsertAsset(orBackground(r.Context), *r.Config, projectID, r.GetGAI(), assetID, r.ID); err != nil {
 		return fmt.Errorf("inserting asset to Config db: %v", err)
 	}
 	return nil
@@ -196,7 +277,12 @@ type Root struct {
 	Rooms    map[string]Room
 	Switches []asset.FunctionalNode
 
-	Config *apiserver.Configuration
+	// Excluded holds the supported devices rejected by the asset filter. No assets are created
+	// for them.
+	Excluded []Device
+
+	Config  *apiserver.Configuration
+	Context context.Context
 }
 
 func (r *Root) GetDevices() []asset.Asset {
@@ -207,6 +293,48 @@ func (r *Root) GetDevices() []asset.Asset {
 	return devices
 }
 
+// ForProject returns the part of the structure that belongs to the project according to the
+// project filters of the configuration. Rooms without devices in the project are left out.
+func (r *Root) ForProject(projectID string) (*Root, error) {
+	if _, ok := r.Config.ProjectFilters[projectID]; !ok {
+		return r, nil
+	}
+	result := &Root{
+		Rooms:    make(map[string]Room),
+		Excluded: r.Excluded,
+		Config:   r.Config,
+		Context:  r.Context,
+	}
+	for _, node := range r.Switches {
+		d, ok := node.(Device)
+		if !ok {
+			continue
+		}
+		in, err := InProject(d, *r.Config, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("checking project of %v: %v", d.GetGAI(), err)
+		}
+		if !in {
+			continue
+		}
+		result.Switches = append(result.Switches, d)
+		room, ok := result.Rooms[d.GetRoomID()]
+		if !ok {
+			original := r.Rooms[d.GetRoomID()]
+			room = Room{
+				ID:       original.ID,
+				Name:     original.Name,
+				Config:   original.Config,
+				Context:  original.Context,
+				Switches: []asset.LocationalNode{},
+			}
+		}
+		room.Switches = append(room.Switches, d)
+		result.Rooms[d.GetRoomID()] = room
+	}
+	return result, nil
+}
+
 func (r *Root) GetName() string {
 	return "myStrom"
 }
@@ -220,15 +348,16 @@ func (r *Root) GetAssetType() string {
 }
 
 func (r *Root) GetGAI() string {
-	return r.GetAssetType()
+	// Scoped to the configuration, so that several configurations can share a project.
+	return fmt.Sprintf("%s_%d", r.GetAssetType(), *r.Config.Id)
 }
 
 func (r *Root) GetAssetID(projectID string) (*int32, error) {
-	return conf.GetAssetId(context.Background(), *r.Config, projectID, r.GetGAI())
+	return conf.GetAssetId(orBackground(r.Context), *r.Config, projectID, r.GetGAI())
 }
 
 func (r *Root) SetAssetID(assetID int32, projectID string) error {
-	if err := conf.InsertAsset(context.Background(), *r.Config, projectID, r.GetGAI(), assetID, ""); err != nil {
+	if err := conf.InsertAsset(orBackground(r.Context), *r.Config, projectID, r.GetGAI(), assetID, ""); err != nil {
 		return fmt.Errorf("inserting asset to Config db: %v", err)
 	}
 	return nil
@@ -253,6 +382,119 @@ func (r *Root) GetFunctionalChildren() []asset.FunctionalNode {
 
 //
 
+func adheresToFilter(device any, deviceID string, config apiserver.Configuration) (bool, error) {
+	if included, ok := DeviceListOverride(config, deviceID); ok {
+		return included, nil
+	}
+	fp, err := utils.StructToMap(device)
+	if err != nil {
+		return false, fmt.Errorf("converting strict to map: %v", err)
+	}
+	adheres, _, err := MatchFilter(fp, config.AssetFilter)
+	if err != nil {
+		return false, err
+	}
+	return adheres, nil
+}
+
+// orBackground returns the context of a node, or the background context for nodes created without
+// one.
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// assetName renders the naming template of the configuration for a device.
+func assetName(config *apiserver.Configuration, name, room, deviceType, id string) string {
+	if config == nil || config.AssetNameTemplate == nil || *config.AssetNameTemplate == "" {
+		return name
+	}
+	rendered := strings.NewReplacer(
+		"{name}", name,
+		"{room}", room,
+		"{type}", deviceType,
+		"{id}", id,
+	).Replace(*config.AssetNameTemplate)
+	if rendered = strings.TrimSpace(rendered); rendered == "" {
+		return name
+	}
+	return rendered
+}
+
+// DeviceListOverride reports whether the device is listed in the include or exclude device list
+// of the configuration, which take precedence over the asset filter. The exclude list wins if a
+// device is listed in both. Returns false as the second value if neither list contains it.
+func DeviceListOverride(config apiserver.Configuration, deviceID string) (included bool, ok bool) {
+	if config.ExcludeDeviceIds != nil && slices.Contains(*config.ExcludeDeviceIds, deviceID) {
+		return false, true
+	}
+	if config.IncludeDeviceIds != nil && slices.Contains(*config.IncludeDeviceIds, deviceID) {
+		return true, true
+	}
+	return false, false
+}
+
+// InProject reports whether the device belongs to the project according to the project filters of
+// the configuration. Projects without a filter get all devices.
+func InProject(d Device, config apiserver.Configuration, projectID string) (bool, error) {
+	if _, ok := config.ProjectFilters[projectID]; !ok {
+		return true, nil
+	}
+	fp, err := utils.StructToMap(d)
+	if err != nil {
+		return false, fmt.Errorf("converting strict to map: %v", err)
+	}
+	return PropertiesInProject(fp, config, projectID)
+}
+
+// PropertiesInProject is like InProject for a device known only by its filterable properties.
+func PropertiesInProject(properties map[string]string, config apiserver.Configuration, projectID string) (bool, error) {
+	filter, ok := config.ProjectFilters[projectID]
+	if !ok {
+		return true, nil
+	}
+	in, _, err := MatchFilter(properties, filter)
+	return in, err
+}
+
+// MatchFilter evaluates the filter against the filterable properties of a device. It returns
+// whether the device is included and the index of the first rule group that matched it, or -1
+// if no group matched. An empty filter includes every device without a matching group.
+func MatchFilter(properties map[string]string, filter [][]apiserver.FilterRule) (bool, int, error) {
+	if len(filter) == 0 {
+		return true, -1, nil
+	}
+	for i, group := range filter {
+		matches := true
+		for _, rule := range group {
+			match, err := matchRule(properties, rule)
+			if err != nil {
+				return false, -1, err
+			}
+			if !match {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true, i, nil
+		}
+	}
+	return false, -1, nil
+}
+
+// matchRule evaluates a single rule. Negated rules match devices whose property does not match
+// the regex, including devices that lack the property.
+func matchRule(properties map[string]string, rule apiserver.FilterRule) (bool, error) {
+	match, err := common.Filter(apiFilterToCommonFilter([][]apiserver.FilterRule{{rule}}), properties)
+	if err != nil {
+		return false, err
+	}
+	return match != rule.Negate, nil
+}
+
 func apiFilterToCommonFilter(input [][]apiserver.FilterRule) [][]common.FilterRule {
 	result := make([][]common.FilterRule, len(input))
 	for i := 0; i < len(input); i++ {
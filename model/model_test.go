@@ -0,0 +1,227 @@
+package model
+
+import (
+	"mystrom/apiserver"
+	"testing"
+)
+
+func TestAdheresToFilter(t *testing.T) {
+	plug := Switch{ID: "AABBCC", Name: "Kitchen plug", Type: "WS2", RoomID: "r1", RoomName: "Kitchen"}
+	unassigned := Switch{ID: "DDEEFF", Name: "Spare plug", Type: "WS2"}
+
+	tests := []struct {
+		name    string
+		device  Switch
+		config  apiserver.Configuration
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "empty filter includes everything",
+			device: plug,
+			want:   true,
+		},
+		{
+			name:   "matching rule",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "name", Regex: "^Kitchen"}},
+			}},
+			want: true,
+		},
+		{
+			name:   "negated matching rule",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "name", Regex: "^Kitchen", Negate: true}},
+			}},
+			want: false,
+		},
+		{
+			name:   "negated non-matching rule",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "name", Regex: "^Office", Negate: true}},
+			}},
+			want: true,
+		},
+		{
+			name:   "negated rule within a group",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "type", Regex: "^WS2$"}, {Parameter: "room_name", Regex: "Kitchen", Negate: true}},
+			}},
+			want: false,
+		},
+		{
+			name:   "second group matches",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "type", Regex: "^Zero$"}},
+				{{Parameter: "room_id", Regex: "^r1$"}},
+			}},
+			want: true,
+		},
+		{
+			name:   "missing property",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "mac", Regex: ".*"}},
+			}},
+			want: false,
+		},
+		{
+			name:   "missing property with negated rule",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "mac", Regex: ".*", Negate: true}},
+			}},
+			want: true,
+		},
+		{
+			name:   "empty room with negated rule",
+			device: unassigned,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "room_name", Regex: ".+", Negate: true}},
+			}},
+			want: true,
+		},
+		{
+			name:   "invalid regex",
+			device: plug,
+			config: apiserver.Configuration{AssetFilter: [][]apiserver.FilterRule{
+				{{Parameter: "name", Regex: "("}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:   "include list overrides the filter",
+			device: plug,
+			config: apiserver.Configuration{
+				AssetFilter:      [][]apiserver.FilterRule{{{Parameter: "name", Regex: "^Office"}}},
+				IncludeDeviceIds: &[]string{"AABBCC"},
+			},
+			want: true,
+		},
+		{
+			name:   "exclude list overrides the filter",
+			device: plug,
+			config: apiserver.Configuration{
+				AssetFilter:      [][]apiserver.FilterRule{{{Parameter: "name", Regex: "^Kitchen"}}},
+				ExcludeDeviceIds: &[]string{"AABBCC"},
+			},
+			want: false,
+		},
+		{
+			name:   "exclude list overrides the include list",
+			device: plug,
+			config: apiserver.Configuration{
+				IncludeDeviceIds: &[]string{"AABBCC"},
+				ExcludeDeviceIds: &[]string{"AABBCC"},
+			},
+			want: false,
+		},
+		{
+			name:   "device lists of other devices",
+			device: plug,
+			config: apiserver.Configuration{
+				AssetFilter:      [][]apiserver.FilterRule{{{Parameter: "name", Regex: "^Kitchen"}}},
+				IncludeDeviceIds: &[]string{"DDEEFF"},
+				ExcludeDeviceIds: &[]string{"112233"},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := tt.device
+			got, err := device.AdheresToFilter(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AdheresToFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AdheresToFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	template := func(s string) *apiserver.Configuration {
+		return &apiserver.Configuration{AssetNameTemplate: &s}
+	}
+
+	tests := []struct {
+		name   string
+		config *apiserver.Configuration
+		room   string
+		want   string
+	}{
+		{
+			name: "no configuration",
+			room: "Kitchen",
+			want: "Plug",
+		},
+		{
+			name:   "no template",
+			config: &apiserver.Configuration{},
+			room:   "Kitchen",
+			want:   "Plug",
+		},
+		{
+			name:   "empty template",
+			config: template(""),
+			room:   "Kitchen",
+			want:   "Plug",
+		},
+		{
+			name:   "all placeholders",
+			config: template("{room}: {name} ({type}, {id})"),
+			room:   "Kitchen",
+			want:   "Kitchen: Plug (WS2, AABBCC)",
+		},
+		{
+			name:   "repeated placeholder",
+			config: template("{name} {name}"),
+			want:   "Plug Plug",
+		},
+		{
+			name:   "empty room",
+			config: template("{name} ({room})"),
+			want:   "Plug ()",
+		},
+		{
+			name:   "empty room is trimmed",
+			config: template("{room} {name}"),
+			want:   "Plug",
+		},
+		{
+			name:   "only an empty room falls back to the name",
+			config: template("{room}"),
+			want:   "Plug",
+		},
+		{
+			name:   "whitespace falls back to the name",
+			config: template("  "),
+			want:   "Plug",
+		},
+		{
+			name:   "unknown placeholder is kept",
+			config: template("{name} {floor}"),
+			room:   "Kitchen",
+			want:   "Plug {floor}",
+		},
+		{
+			name:   "placeholders are case sensitive",
+			config: template("{Name}"),
+			want:   "{Name}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assetName(tt.config, "Plug", tt.room, "WS2", "AABBCC"); got != tt.want {
+				t.Errorf("assetName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
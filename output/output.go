@@ -0,0 +1,203 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package output listens for output changes in Eliona and hands them to a handler. The connection
+// to Eliona is supervised and re-established with backoff. Changes are handled per device, so
+// that a device slow to answer does not hold up the others.
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"mystrom/eliona"
+	"mystrom/metrics"
+	"sync"
+	"time"
+
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+const (
+	// The delay before reconnecting doubles from minBackoff up to maxBackoff. It is reset once a
+	// connection stayed up for stableAfter.
+	minBackoff  = time.Second
+	maxBackoff  = time.Minute
+	stableAfter = time.Minute
+
+	// maxConcurrent bounds the number of devices whose changes are handled at the same time.
+	maxConcurrent = 8
+)
+
+// Handler acts on an output change of an asset.
+type Handler func(ctx context.Context, output api.Data)
+
+var (
+	stateMu   sync.Mutex
+	connected bool
+	lastErr   error
+)
+
+// Status returns nil while connected to Eliona, otherwise the reason why not.
+func Status() error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	switch {
+	case connected:
+		return nil
+	case lastErr != nil:
+		return fmt.Errorf("not connected to Eliona to listen for output changes: %v", lastErr)
+	default:
+		return errors.New("not connected to Eliona to listen for output changes")
+	}
+}
+
+func setConnected(err error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	connected = err == nil
+	if err != nil {
+		lastErr = err
+	}
+	metrics.SetOutputListenerConnected(connected)
+}
+
+// Listen passes the output changes to the handler until the context is cancelled. Changes that
+// are being handled are finished before Listen returns.
+func Listen(ctx context.Context, handle Handler) {
+	d := newDispatcher(handle)
+	defer d.wait()
+
+	failures := 0
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		if attempt > 0 {
+			metrics.WebsocketReconnect()
+		}
+		connectedAt := time.Now()
+		err := listen(ctx, d)
+		if ctx.Err() != nil {
+			setConnected(ctx.Err())
+			return
+		}
+		if err == nil {
+			err = errors.New("connection closed by Eliona")
+		}
+		setConnected(err)
+		if time.Since(connectedAt) >= stableAfter {
+			failures = 0
+		}
+		failures++
+		delay := backoff(failures)
+		log.Error("output", "listening for output changes: %v, reconnecting in %v", err, delay.Round(time.Millisecond))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// listen connects to Eliona and dispatches the changes until the connection ends.
+func listen(ctx context.Context, d *dispatcher) error {
+	outputs, errs, err := eliona.ListenForOutputChanges(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %v", err)
+	}
+	setConnected(nil)
+	log.Info("output", "listening for output changes")
+	for output := range outputs {
+		if cr := output.ClientReference.Get(); cr != nil && *cr == eliona.ClientReference {
+			// Just an echoed value this app sent.
+			metrics.EchoSkipped()
+			continue
+		}
+		d.dispatch(ctx, output)
+	}
+	return <-errs
+}
+
+// backoff returns the delay before the next connection attempt, with some randomness added, so
+// that replicas do not reconnect at the same time.
+func backoff(failures int) time.Duration {
+	d := min(minBackoff<<min(failures-1, 16), maxBackoff)
+	return d/2 + rand.N(d/2)
+}
+
+// dispatcher hands output changes to the handler per asset. Each asset with a change has a
+// worker that handles it. Only the latest change that arrives meanwhile is kept, as it is the
+// value the user wants; the worker handles it next and ends once none is waiting.
+type dispatcher struct {
+	handle Handler
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	active  map[int32]bool
+	pending map[int32]api.Data
+}
+
+func newDispatcher(handle Handler) *dispatcher {
+	return &dispatcher{
+		handle:  handle,
+		slots:   make(chan struct{}, maxConcurrent),
+		active:  make(map[int32]bool),
+		pending: make(map[int32]api.Data),
+	}
+}
+
+func (d *dispatcher) dispatch(ctx context.Context, output api.Data) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.active[output.AssetId] {
+		d.active[output.AssetId] = true
+		d.wg.Add(1)
+		go d.work(ctx, output)
+		return
+	}
+	if _, ok := d.pending[output.AssetId]; ok {
+		log.Debug("output", "output change for asset id %v superseded by a newer one", output.AssetId)
+		metrics.OutputCommandSuperseded()
+	}
+	d.pending[output.AssetId] = output
+}
+
+func (d *dispatcher) work(ctx context.Context, output api.Data) {
+	defer d.wg.Done()
+	for {
+		select {
+		case d.slots <- struct{}{}:
+			d.handle(ctx, output)
+			<-d.slots
+		case <-ctx.Done():
+		}
+
+		d.mu.Lock()
+		next, ok := d.pending[output.AssetId]
+		if !ok || ctx.Err() != nil {
+			delete(d.pending, output.AssetId)
+			delete(d.active, output.AssetId)
+			d.mu.Unlock()
+			return
+		}
+		delete(d.pending, output.AssetId)
+		d.mu.Unlock()
+		output = next
+	}
+}
+
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}